@@ -0,0 +1,143 @@
+package bnf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action computes the value of a rule match from the values of its parts.
+type Action func(ActionContext) (any, error)
+
+// ActionContext describes the rule match an Action is run for.
+type ActionContext struct {
+	Rule   string   // name of the matched rule
+	Node   *ASTNode // parse tree node of the match
+	Text   string   // matched text
+	Pos    int      // byte offset where the match starts
+	End    int      // byte offset where the match ends
+	Values []any    // values of the children: action results for rules, matched text for literals and regexes
+}
+
+// Value returns the value of the i-th child, or nil when there is no such child.
+func (c ActionContext) Value(i int) any {
+	if i < 0 || i >= len(c.Values) {
+		return nil
+	}
+	return c.Values[i]
+}
+
+// SetAction attaches a semantic action to a rule, replacing the previous one.
+// A nil action removes it.
+func (g *Grammar) SetAction(rule string, action Action) {
+	if g.actions == nil {
+		g.actions = map[string]Action{}
+	}
+	if action == nil {
+		delete(g.actions, rule)
+		return
+	}
+	g.actions[rule] = action
+}
+
+// Evaluate parses the input and returns the value of the start rule, computed
+// by the semantic actions as rules match. The value of a rule without an
+// action is the value of its only child, or its matched text when it has
+// several. An error returned by an action is reported as a *ParseError
+// positioned at the start of the rule match, wrapping that error.
+//
+// Actions run once for every match of a rule at a position, including
+// matches of alternatives the chosen derivation leaves out, so they should be
+// free of side effects. Only the values and errors of the chosen derivation
+// make it into the result.
+func (g *Grammar) Evaluate(input string) (any, error) {
+	for rule := range g.actions {
+		if _, ok := g.Rules[rule]; !ok {
+			return nil, fmt.Errorf("action set for unknown rule: %s", rule)
+		}
+	}
+	rule, ok := g.Rules[g.Start]
+	if !ok {
+		return nil, fmt.Errorf("unknown start rule: %s", g.Start)
+	}
+
+	ctx := NewContext(input)
+	ctx.actions = g.actions
+	ctx.values = map[*ASTNode]evaluated{}
+	tree, err := g.parse(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := ctx.values[tree]
+	if !ok {
+		ctx.evaluate(tree)
+		e = ctx.values[tree]
+	}
+	if pe, ok := e.err.(*ParseError); ok {
+		// the start rule is matched without entering it
+		pe.RuleStack = append([]string{g.Start}, pe.RuleStack...)
+	}
+	return e.value, e.err
+}
+
+// evaluated is the value of a parse tree node, or the error of an action on
+// the way to it.
+type evaluated struct {
+	value any
+	err   error
+}
+
+// value returns the value of n, running the action of its rule unless that
+// happened when the rule matched.
+func (ctx *context) value(n *ASTNode) evaluated {
+	if n.Type == "TERMINAL" || n.Type == "REGEX" {
+		return evaluated{value: n.Value}
+	}
+	if e, ok := ctx.values[n]; ok {
+		return e
+	}
+	// tokens of the scanner, which no rule match evaluated
+	ctx.push(n.Type)
+	defer ctx.pop()
+	ctx.evaluate(n)
+	return ctx.values[n]
+}
+
+// evaluate runs the action of the rule of n, just matched, on the values of
+// its children.
+func (ctx *context) evaluate(n *ASTNode) {
+	values := make([]any, len(n.Children))
+	for i, child := range n.Children {
+		e := ctx.value(child)
+		if e.err != nil {
+			ctx.values[n] = e
+			return
+		}
+		values[i] = e.value
+	}
+
+	text := ctx.input[n.Pos:n.End]
+	action, ok := ctx.actions[n.Type]
+	switch {
+	case !ok && len(values) == 1:
+		ctx.values[n] = evaluated{value: values[0]}
+	case !ok:
+		ctx.values[n] = evaluated{value: text}
+	default:
+		v, err := action(ActionContext{Rule: n.Type, Node: n, Text: text, Pos: n.Pos, End: n.End, Values: values})
+		if err != nil {
+			line, col := lineCol(ctx.input, n.Pos)
+			firstLine, _, _ := strings.Cut(text, "\n")
+			err = &ParseError{
+				Pos:       n.Pos,
+				Line:      line,
+				Column:    col,
+				RuleStack: slices.Clone(ctx.stack),
+				Width:     max(1, utf8.RuneCountInString(firstLine)),
+				Err:       err,
+			}
+		}
+		ctx.values[n] = evaluated{value: v, err: err}
+	}
+}
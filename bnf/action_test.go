@@ -0,0 +1,184 @@
+package bnf_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const arithmetic = `
+<expr>   ::= <expr> "+" <term> | <expr> "-" <term> | <term>
+<term>   ::= <term> "*" <factor> | <term> "/" <factor> | <factor>
+<factor> ::= <number> | "(" <expr> ")"
+<number> ::= /[0-9]+/
+`
+
+var errDivByZero = errors.New("division by zero")
+
+func arithmeticGrammar(t *testing.T) *bnf.Grammar {
+	g, err := bnf.LoadGrammarString(arithmetic)
+	assert.NoError(t, err)
+
+	binary := func(c bnf.ActionContext) (any, error) {
+		if len(c.Values) == 1 {
+			return c.Value(0), nil
+		}
+		a, b := c.Value(0).(int), c.Value(2).(int)
+		switch c.Value(1) {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		}
+		if b == 0 {
+			return nil, errDivByZero
+		}
+		return a / b, nil
+	}
+	g.SetAction("expr", binary)
+	g.SetAction("term", binary)
+	g.SetAction("factor", func(c bnf.ActionContext) (any, error) {
+		if len(c.Values) == 3 {
+			return c.Value(1), nil
+		}
+		return c.Value(0), nil
+	})
+	g.SetAction("number", func(c bnf.ActionContext) (any, error) {
+		return strconv.Atoi(c.Text)
+	})
+	return g
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+	g := arithmeticGrammar(t)
+
+	for input, want := range map[string]int{
+		"42":          42,
+		"1+2*3":       7,
+		"(1+2)*3":     9,
+		"10-4-3":      3,
+		"100/(2+3)/2": 10,
+	} {
+		v, err := g.Evaluate(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, v, input)
+	}
+}
+
+func TestEvaluate_Defaults(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+
+	// without actions rules pass their only child on, or their text
+	v, err := g.Evaluate("7:38pm")
+	assert.NoError(t, err)
+	assert.Equal(t, "7:38pm", v)
+
+	g.SetAction("time", func(c bnf.ActionContext) (any, error) {
+		return c.Values, nil
+	})
+	v, err = g.Evaluate("12am")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"12", "am"}, v)
+
+	g.SetAction("time", nil)
+	g.SetAction("hour", func(c bnf.ActionContext) (any, error) {
+		return [2]int{c.Pos, c.End}, nil
+	})
+	v, err = g.Evaluate("7")
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int{0, 1}, v)
+}
+
+func TestEvaluate_ActionError(t *testing.T) {
+	t.Parallel()
+	g := arithmeticGrammar(t)
+
+	_, err := g.Evaluate("1+(4/0)")
+	var pe *bnf.ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.ErrorIs(t, err, errDivByZero)
+		assert.Equal(t, 3, pe.Pos)
+		assert.Equal(t, 1, pe.Line)
+		assert.Equal(t, 4, pe.Column)
+		assert.Equal(t, 3, pe.Width)
+		assert.Equal(t, []string{"expr", "term", "factor", "expr", "term"}, pe.RuleStack)
+		assert.Contains(t, pe.Pretty("1+(4/0)"), "Error: division by zero")
+	}
+
+	// syntax errors stay plain parse errors
+	_, err = g.Evaluate("1+")
+	assert.ErrorAs(t, err, &pe)
+	assert.NoError(t, pe.Err)
+
+	g.SetAction("nope", func(bnf.ActionContext) (any, error) { return nil, nil })
+	_, err = g.Evaluate("1")
+	assert.ErrorContains(t, err, "unknown rule: nope")
+}
+
+func TestEvaluate_MatchedText(t *testing.T) {
+	t.Parallel()
+
+	// skipped and dropped text is part of what a rule matched
+	g, err := bnf.LoadGrammarString(`
+sum    ::= num "+" num
+pair   ::= num (-",") num
+@lexical
+num    ::= /[0-9]+/
+@skip  ::= " "*
+`)
+	assert.NoError(t, err)
+	g.SetAction("sum", func(c bnf.ActionContext) (any, error) {
+		return c.Text, nil
+	})
+	v, err := g.Evaluate("1 + 2")
+	assert.NoError(t, err)
+	assert.Equal(t, "1 + 2", v)
+
+	g.SetAction("sum", nil)
+	v, err = g.Evaluate("1 +2")
+	assert.NoError(t, err)
+	assert.Equal(t, "1 +2", v)
+}
+
+func TestEvaluate_WhileMatching(t *testing.T) {
+	t.Parallel()
+
+	// a runs as it matches, its error is dropped with the alternative
+	g, err := bnf.LoadGrammarString(`
+s ::= a "x" | b
+a ::= "1"
+b ::= "1"
+`)
+	assert.NoError(t, err)
+	g.SetAction("a", func(bnf.ActionContext) (any, error) { return nil, errDivByZero })
+	g.SetAction("b", func(bnf.ActionContext) (any, error) { return "b", nil })
+	v, err := g.Evaluate("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v)
+
+	_, err = g.Evaluate("1x")
+	assert.ErrorIs(t, err, errDivByZero)
+
+	// tokens of the scanner have values as well
+	g, err = bnf.LoadGrammarString(`
+sum           ::= NUMBER "+" NUMBER
+@token NUMBER ::= /[0-9]+/
+@skip         ::= " "*
+`)
+	assert.NoError(t, err)
+	g.SetAction("NUMBER", func(c bnf.ActionContext) (any, error) { return strconv.Atoi(c.Text) })
+	g.SetAction("sum", func(c bnf.ActionContext) (any, error) { return c.Value(0).(int) + c.Value(2).(int), nil })
+	v, err = g.Evaluate("12 + 30")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
@@ -0,0 +1,122 @@
+package bnf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotation_Parse(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+time          ::= hour (-":") minute ampm?
+@leaf hour    ::= digit? digit
+minute        ::= digit digit
+@inline digit ::= [0-9]
+ampm          ::= _space ("am" | "pm")
+_space        ::= " "?
+`)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("12:30 pm")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hour", "minute", "ampm"}, childTypes(tree))
+	assert.Equal(t, &bnf.ASTNode{Type: "hour", Value: "12", Pos: 0, End: 2}, tree.Children[0])
+	assert.Equal(t, []string{"REGEX", "REGEX"}, childTypes(tree.Children[1]))
+	assert.Equal(t, []string{"TERMINAL"}, childTypes(tree.Children[2]))
+
+	ok, err := g.Match("7:05")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestAnnotation_Match(t *testing.T) {
+	t.Parallel()
+
+	// annotations shape the tree, the language stays the same
+	for _, a := range []string{"", "@leaf", "@inline", "@hidden", "@lexical"} {
+		g, err := bnf.LoadGrammarString(`
+s      ::= hour "1"
+` + a + ` hour ::= digit? digit
+digit  ::= [0-9]
+`)
+		if !assert.NoError(t, err, a) {
+			continue
+		}
+		for in, want := range map[string]bool{"11": true, "121": true, "1231": false, "1": false} {
+			ok, _ := g.Match(in)
+			assert.Equal(t, want, ok, "%s on %q", a, in)
+		}
+	}
+}
+
+func TestAnnotation_InlineAndHidden(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+list   ::= "(" items ")"
+@inline
+items  ::= item (-"," item)*
+item   ::= word | list
+@hidden word ::= [a-z]+ | _num
+_num   ::= [0-9]+
+`)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("(a,(b,1),c)")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TERMINAL", "item", "item", "item", "TERMINAL"}, childTypes(tree))
+	assert.Empty(t, tree.Children[1].Children)
+	inner := tree.Children[2].Children[0]
+	assert.Equal(t, "list", inner.Type)
+	assert.Equal(t, []string{"TERMINAL", "item", "item", "TERMINAL"}, childTypes(inner))
+}
+
+func TestAnnotation_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		err     string
+	}{
+		{"@inline @hidden s ::= \"a\"", "rule s cannot be both @inline and @hidden"},
+		{"@token\n@inline s ::= \"a\"", "rule s cannot be both @token and @inline"},
+		{"@leaf @token s ::= \"a\"", "rule s cannot be both @leaf and @token"},
+		{"@flatten s ::= \"a\"", "unknown directive: @flatten"},
+		{"time ::= hour -\":\" minute", `ambiguous "-" after an operand: write a - b for a difference or a (-b) to leave b out of the parse tree`},
+	}
+	for _, tt := range tests {
+		_, err := bnf.LoadGrammarString(tt.grammar)
+		assert.EqualError(t, err, tt.err, tt.grammar)
+	}
+
+	g, err := bnf.LoadGrammarString("s ::= \"(\" l \")\"\n@inline l ::= \"a\" l?")
+	assert.NoError(t, err)
+	assert.EqualError(t, g.GenerateGoTypes(&bytes.Buffer{}, bnf.GoOptions{}), "gen-go types do not support the recursive @inline rule l")
+}
+
+func TestAnnotation_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `pair                 ::= key (-":") value (-"," (-(" " | "\t")))*` + "\n" +
+		`@inline @lexical key ::= [a-z]+` + "\n" +
+		`@hidden value        ::= key - "x"` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(src))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: bnf.ExportW3C})
+	assert.NoError(t, err)
+	assert.Equal(t, `pair  ::= key ":" value ("," (" " | #x9))*`, strings.Split(buf.String(), "\n")[0])
+}
@@ -3,17 +3,35 @@ package bnf
 import (
 	"fmt"
 	"regexp"
+	"slices"
 )
 
 // GrammarAST represents the raw AST of a BNF grammar before it is built into a Grammar object.
 type GrammarAST struct {
-	Rules []*RuleAST
+	Rules    []*RuleAST
+	Imports  []*ImportAST
+	Skip     *RuleAST  // the @skip directive, whose Expr syntactic rules skip between their elements
+	Comments []Comment // comments of the source, kept for printing
+}
+
+// ImportAST represents an @import directive. Rules of an import with an alias
+// are referenced with the alias as prefix, like common.digit.
+type ImportAST struct {
+	Path  string
+	Alias string
+	Line  int
 }
 
 // RuleAST represents a single rule in the GrammarAST.
 type RuleAST struct {
-	Name string
-	Expr ExprAST
+	Name        string
+	Params      []string // parameters of a parameterized rule, like X and S of <sep-list X S>
+	Annotations []string // annotations written before the rule, like lexical for @lexical
+	Expr        ExprAST
+
+	Bracketed bool // the name was written as <name>
+	Line      int  // first line of the rule in the source
+	EndLine   int  // last line of the rule in the source
 }
 
 // ExprAST is a marker interface for all AST expression nodes.
@@ -44,26 +62,97 @@ type (
 
 	// StringAST represents a literal string terminal.
 	StringAST struct {
-		Value string
+		Value      string
+		IgnoreCase bool // written as "..."i, matched with Unicode case folding
 	}
 
 	// RegexAST represents a regular expression pattern terminal.
 	RegexAST struct {
 		Pattern string
 	}
+
+	// CharClassAST represents a set of characters matching a single rune,
+	// written as 'a'..'z', [a-zA-Z_], [^"\n] or \p{L}.
+	CharClassAST struct {
+		Negated    bool
+		Ranges     []RuneRange
+		Categories []string // Unicode categories or scripts, like L or Greek
+	}
+
+	// BuiltinAST represents a rule of the standard library, like DIGIT,
+	// implemented natively.
+	BuiltinAST struct {
+		Name string
+	}
+
+	// AnyAST represents the wildcard . matching any single character.
+	AnyAST struct{}
+
+	// EndAST represents the end of input anchor, written as EOF or $.
+	EndAST struct {
+		word bool // written as EOF, which a rule named EOF takes over
+	}
+
+	// NotAST represents a negative lookahead (e.g., !"*/"), succeeding without
+	// consuming input when Node does not match.
+	NotAST struct {
+		Node ExprAST
+	}
+
+	// DropAST represents an element left out of the parse tree (e.g., -":"),
+	// matching what Node matches.
+	DropAST struct {
+		Node ExprAST
+	}
+
+	// DiffAST represents a difference (e.g., <ident> - <keyword>), matching
+	// what Node matches unless Except matches the same text.
+	DiffAST struct {
+		Node   ExprAST
+		Except ExprAST
+	}
+
+	// SepListAST represents a separated list (e.g., <arg> % ","): one or more
+	// Node separated by Sep, or zero or more with Min 0. The separators are
+	// left out of the parse tree.
+	SepListAST struct {
+		Node     ExprAST
+		Sep      ExprAST
+		Min      int  // 0 for %*, 1 for %
+		Trailing bool // a separator may follow the last element, written %% or %%*
+	}
+
+	// CallAST represents a use of a parameterized rule (e.g., <sep-list <id> ",">).
+	CallAST struct {
+		Name string
+		Args []ExprAST
+	}
 )
 
+// RuneRange is an inclusive range of characters of a CharClassAST.
+type RuneRange struct {
+	Lo, Hi rune
+}
+
 // BuildGrammar converts a raw GrammarAST into a functional Grammar object with linked rules.
 func BuildGrammar(ast *GrammarAST) (*Grammar, error) {
 	rules := map[string]*Rule{}
+	var order []string
 
 	if len(ast.Rules) == 0 {
 		return nil, fmt.Errorf("empty grammar")
 	}
+	ast, err := expandParams(ast)
+	if err != nil {
+		return nil, err
+	}
 
 	// 1. create rules
 	for _, r := range ast.Rules {
-		rules[r.Name] = &Rule{Name: r.Name}
+		if _, ok := rules[r.Name]; !ok {
+			order = append(order, r.Name)
+		}
+		rules[r.Name] = &Rule{Name: r.Name, tree: ruleTree(r)}
 	}
 
 	// 2. build expr
@@ -75,15 +164,36 @@ func BuildGrammar(ast *GrammarAST) (*Grammar, error) {
 		rules[r.Name].Expr = expr
 	}
 
-	return &Grammar{
+	g := &Grammar{
 		Start: ast.Rules[0].Name,
 		Rules: rules,
-	}, nil
+		order: order,
+	}
+	lexical := lexicalRules(ast)
+	if ast.Skip != nil {
+		skip, err := buildNode(ast.Skip.Expr, rules)
+		if err != nil {
+			return nil, err
+		}
+		g.skip = &skipper{Node: skip}
+		for _, name := range order {
+			if !lexical[name] {
+				setSkip(rules[name].Expr, g.skip)
+			}
+		}
+	}
+	if slices.ContainsFunc(ast.Rules, func(r *RuleAST) bool { return slices.Contains(r.Annotations, "token") }) {
+		g.scanner = newScanner(ast, rules, order, lexical, g.skip)
+	}
+	return g, nil
 }
 
 func buildNode(e ExprAST, rules map[string]*Rule) (node, error) {
 	switch t := e.(type) {
 	case *StringAST:
+		if t.IgnoreCase {
+			return &caselessTerminal{Value: t.Value}, nil
+		}
 		return &terminal{Value: t.Value}, nil
 
 	case *RegexAST:
@@ -93,6 +203,50 @@ func buildNode(e ExprAST, rules map[string]*Rule) (node, error) {
 		}
 		return &Regex{Re: re}, nil
 
+	case *CharClassAST:
+		return newCharClass(t)
+
+	case *BuiltinAST:
+		b := lookupBuiltin(t.Name)
+		if b == nil {
+			return nil, fmt.Errorf("unknown builtin rule: %s", t.Name)
+		}
+		return b, nil
+
+	case *AnyAST:
+		return &anyRune{}, nil
+
+	case *EndAST:
+		return &endOfInput{}, nil
+
+	case *NotAST:
+		n, err := buildNode(t.Node, rules)
+		if err != nil {
+			return nil, err
+		}
+		return &notPredicate{Node: n}, nil
+
+	case *DropAST:
+		n, err := buildNode(t.Node, rules)
+		if err != nil {
+			return nil, err
+		}
+		return &dropped{Node: n}, nil
+
+	case *DiffAST:
+		n, err := buildNode(t.Node, rules)
+		if err != nil {
+			return nil, err
+		}
+		except, err := buildNode(t.Except, rules)
+		if err != nil {
+			return nil, err
+		}
+		return &difference{Node: n, Except: except}, nil
+
+	case *SepListAST:
+		return buildSepList(t, rules)
+
 	case *IdentAST:
 		return &nonTerminal{Name: t.Name, Rule: rules[t.Name]}, nil
 
@@ -123,6 +277,9 @@ func buildNode(e ExprAST, rules map[string]*Rule) (node, error) {
 		if err != nil {
 			return nil, err
 		}
+		if t.Min == 0 && t.Max == 1 {
+			return &optional{Node: n}, nil
+		}
 		return &repeat{
 			Node: n,
 			Min:  t.Min,
@@ -0,0 +1,106 @@
+// Package bnftest provides helpers for fuzz testing code that consumes inputs
+// described by a BNF grammar.
+//
+// Fuzzer bytes are not used as inputs directly. Instead they are decoded as the
+// sequence of derivation choices taken by the sentence generator (which
+// alternative, how many repetitions, which regex character), so every byte
+// string maps to a grammatically valid input and the fuzzer's mutations turn
+// into structural changes of the derivation.
+package bnftest
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Options configure FuzzGrammarWith and DecodeWith, the zero value is what
+// FuzzGrammar and Decode use.
+// Running out of fuzzer bytes makes the remaining choices pick the first
+// viable alternative and the minimal number of repetitions.
+type Options struct {
+	Seeds    int                 // generated sentences added to the seed corpus (default 32)
+	Generate bnf.GenerateOptions // how inputs are generated and decoded
+}
+
+// FuzzGrammar seeds f with derivations of generated sentences and fuzzes target
+// with grammar-conforming inputs decoded from the fuzzer bytes.
+func FuzzGrammar(f *testing.F, g *bnf.Grammar, target func(string)) {
+	f.Helper()
+	FuzzGrammarWith(f, g, Options{}, target)
+}
+
+// FuzzGrammarWith is FuzzGrammar with options.
+func FuzzGrammarWith(f *testing.F, g *bnf.Grammar, opts Options, target func(string)) {
+	f.Helper()
+	if opts.Seeds <= 0 {
+		opts.Seeds = 32
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	seen := map[string]bool{}
+	for range opts.Seeds {
+		src := &byteSource{record: r}
+		s, err := g.Generate(rand.New(src), opts.Generate)
+		if err != nil {
+			f.Fatalf("cannot generate from grammar: %v", err)
+		}
+		if !seen[s] {
+			seen[s] = true
+			f.Add(src.data)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := DecodeWith(g, data, opts)
+		if err != nil {
+			t.Skip(err)
+		}
+		// regex terminals are matched greedily, so a generated sentence can
+		// occasionally fall outside of what the matcher accepts
+		if ok, _ := g.Match(s); !ok {
+			t.Skip("decoded input is rejected by the grammar")
+		}
+		target(s)
+	})
+}
+
+// Decode turns an arbitrary byte string into a sentence of the grammar by using
+// the bytes as derivation choices. The same bytes always decode to the same sentence.
+func Decode(g *bnf.Grammar, data []byte) (string, error) {
+	return DecodeWith(g, data, Options{})
+}
+
+// DecodeWith is Decode with options, which must be the same to decode the
+// same bytes to the same sentence.
+func DecodeWith(g *bnf.Grammar, data []byte, opts Options) (string, error) {
+	return g.Generate(rand.New(&byteSource{data: data}), opts.Generate)
+}
+
+// byteSource is a rand.Source consuming one byte per random number. When record
+// is set it draws the bytes from it and appends them to data instead, so the
+// same sentence can be decoded from data later.
+type byteSource struct {
+	data   []byte
+	pos    int
+	record *rand.Rand
+}
+
+func (s *byteSource) Uint64() uint64 {
+	var b byte
+	switch {
+	case s.record != nil:
+		b = byte(s.record.Uint64())
+		s.data = append(s.data, b)
+	case s.pos < len(s.data):
+		b = s.data[s.pos]
+		s.pos++
+	default:
+		// past the data draw the smallest number rand.Rand can return, zero
+		// would be rejected by IntN over and over for sizes not a power of two
+		return 1 << 32
+	}
+	// spread the byte over all bits, so both integer and float draws follow it
+	return uint64(b) * 0x0101010101010101
+}
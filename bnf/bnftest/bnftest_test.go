@@ -0,0 +1,82 @@
+package bnftest
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+func hourGrammar(t testing.TB) *bnf.Grammar {
+	g, err := bnf.LoadGrammarFile("../../examples/hour.bnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+	g := hourGrammar(t)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		data := make([]byte, r.IntN(20))
+		for i := range data {
+			data[i] = byte(r.Uint32())
+		}
+
+		s, err := Decode(g, data)
+		assert.NoError(t, err)
+		ok, err := g.Match(s)
+		assert.True(t, ok, s)
+		assert.NoError(t, err)
+
+		again, _ := Decode(g, data)
+		assert.Equal(t, s, again)
+	}
+
+	// no bytes left means the shortest derivation
+	s, err := Decode(g, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", s)
+}
+
+func TestDecode_Exhausted(t *testing.T) {
+	t.Parallel()
+
+	// choices among sizes not a power of two once the bytes run out
+	g, err := bnf.LoadGrammarString(`<S> ::= /[a-z]/ [b-y] /a{1,3}/`)
+	assert.NoError(t, err)
+	s, err := Decode(g, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "aba", s)
+
+	s, err = DecodeWith(g, []byte{0, 0, 7}, Options{Generate: bnf.GenerateOptions{MaxRepeat: 5}})
+	assert.NoError(t, err)
+	ok, _ := g.Match(s)
+	assert.True(t, ok, s)
+}
+
+func TestByteSource_Replay(t *testing.T) {
+	t.Parallel()
+	g := hourGrammar(t)
+
+	rec := &byteSource{record: rand.New(rand.NewPCG(3, 4))}
+	want, err := g.Generate(rand.New(rec), bnf.GenerateOptions{})
+	assert.NoError(t, err)
+
+	got, err := Decode(g, rec.data)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func FuzzGrammar_Hour(f *testing.F) {
+	g := hourGrammar(f)
+	FuzzGrammar(f, g, func(s string) {
+		if ok, err := g.Match(s); !ok {
+			panic(err)
+		}
+	})
+}
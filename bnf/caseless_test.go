@@ -0,0 +1,67 @@
+package bnf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaseless_Parse(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<query> ::= "select"i " " <name> " " "from"i " " <name>
+<name>  ::= [a-z]+
+`)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("SeLeCt a FROM b")
+	assert.NoError(t, err)
+	assert.Equal(t, "SeLeCt", tree.Children[0].Value)
+	assert.Equal(t, "FROM", tree.Children[4].Value)
+
+	_, err = g.Parse("selekt a from b")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, []string{`"select"i`}, perr.Expected)
+	}
+
+	// a plain literal stays case-sensitive, also right before an identifier
+	g, err = bnf.LoadGrammarString(`<s> ::= "a"ident` + "\n" + `<ident> ::= "i"`)
+	assert.NoError(t, err)
+	ok, _ := g.Match("ai")
+	assert.True(t, ok)
+	ok, _ = g.Match("Ai")
+	assert.False(t, ok)
+}
+
+func TestCaseless_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `ampm ::= "am"i | "p.m."i` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(src))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	want := map[string]string{
+		bnf.ExportISO:  `ampm = ("A" | "a"), ("M" | "m") | ("P" | "p"), ".", ("M" | "m"), "." ;`,
+		bnf.ExportABNF: `ampm = "am" / "p.m."`,
+		bnf.ExportW3C:  `ampm ::= [Aa] [Mm] | [Pp] "." [Mm] "."`,
+		bnf.ExportPEG:  `ampm <- "am"i / "p.m."i`,
+	}
+	for format, line := range want {
+		var buf bytes.Buffer
+		_, err := bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: format})
+		assert.NoError(t, err)
+		assert.Equal(t, line, strings.TrimSpace(buf.String()), format)
+	}
+}
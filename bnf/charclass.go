@@ -0,0 +1,201 @@
+package bnf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charClass matches a single rune of a set of characters.
+type charClass struct {
+	CharClassAST
+	tables []*unicode.RangeTable // tables of the Unicode categories
+	re     *Regex
+}
+
+func newCharClass(c *CharClassAST) (*charClass, error) {
+	if len(c.Ranges) == 0 && len(c.Categories) == 0 {
+		return nil, fmt.Errorf("empty character class")
+	}
+	out := &charClass{CharClassAST: *c}
+	for _, r := range c.Ranges {
+		if r.Lo > r.Hi {
+			return nil, fmt.Errorf("invalid character range %s", c)
+		}
+	}
+	for _, name := range c.Categories {
+		table := unicodeTable(name)
+		if table == nil {
+			return nil, fmt.Errorf("unknown Unicode category or script: %s", name)
+		}
+		out.tables = append(out.tables, table)
+	}
+	out.re = stdPattern(c.pattern())
+	return out, nil
+}
+
+func unicodeTable(name string) *unicode.RangeTable {
+	if t, ok := unicode.Categories[name]; ok {
+		return t
+	}
+	return unicode.Scripts[name]
+}
+
+func (c *charClass) contains(r rune) bool {
+	in := false
+	for _, rr := range c.Ranges {
+		if rr.Lo <= r && r <= rr.Hi {
+			in = true
+			break
+		}
+	}
+	if !in {
+		in = unicode.IsOneOf(c.tables, r)
+	}
+	return in != c.Negated
+}
+
+func (c *charClass) match(ctx *context, pos int) ([]MatchResult, error) {
+	r, size := utf8.DecodeRuneInString(ctx.input[pos:])
+	if size == 0 || !c.contains(r) {
+		return nil, nil
+	}
+	return []MatchResult{{
+		End:   pos + size,
+		Nodes: []*ASTNode{{Type: "REGEX", Value: ctx.input[pos : pos+size], Pos: pos, End: pos + size}},
+	}}, nil
+}
+
+func (c *charClass) Expect() []string {
+	return []string{c.String()}
+}
+
+func (c *charClass) regex() *Regex {
+	return c.re
+}
+
+// String returns the class in grammar notation, like [a-z] or \p{L}.
+func (c *CharClassAST) String() string {
+	if len(c.Ranges) == 0 && len(c.Categories) == 1 {
+		if c.Negated {
+			return `\P{` + c.Categories[0] + "}"
+		}
+		return `\p{` + c.Categories[0] + "}"
+	}
+	var sb strings.Builder
+	sb.WriteString("[")
+	if c.Negated {
+		sb.WriteString("^")
+	}
+	for _, r := range c.Ranges {
+		sb.WriteString(classRuneString(r.Lo))
+		if r.Hi != r.Lo {
+			sb.WriteString("-" + classRuneString(r.Hi))
+		}
+	}
+	for _, name := range c.Categories {
+		sb.WriteString(`\p{` + name + "}")
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// pattern returns the class in Go regexp syntax.
+func (c *CharClassAST) pattern() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	if c.Negated {
+		sb.WriteString("^")
+	}
+	for _, r := range c.Ranges {
+		fmt.Fprintf(&sb, `\x{%x}`, r.Lo)
+		if r.Hi != r.Lo {
+			fmt.Fprintf(&sb, `-\x{%x}`, r.Hi)
+		}
+	}
+	for _, name := range c.Categories {
+		sb.WriteString(`\p{` + name + "}")
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func classRuneString(r rune) string {
+	switch r {
+	case '\\', ']', '[', '^', '-':
+		return `\` + string(r)
+	}
+	if s, ok := escapeRune(r); ok {
+		return s
+	}
+	return string(r)
+}
+
+// parseCharClass parses a character class token: [...] or \p{Name}.
+func parseCharClass(text string) (*CharClassAST, error) {
+	if name, ok := strings.CutPrefix(text, `\p{`); ok {
+		return &CharClassAST{Categories: []string{strings.TrimSuffix(name, "}")}}, nil
+	}
+	if name, ok := strings.CutPrefix(text, `\P{`); ok {
+		return &CharClassAST{Negated: true, Categories: []string{strings.TrimSuffix(name, "}")}}, nil
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(text, "["), "]")
+	c := &CharClassAST{}
+	if rest, ok := strings.CutPrefix(body, "^"); ok {
+		c.Negated = true
+		body = rest
+	}
+	// next returns the next character of the class, or the category of \p{Name}
+	next := func() (rune, string, error) {
+		r, size := utf8.DecodeRuneInString(body)
+		body = body[size:]
+		if r != '\\' {
+			return r, "", nil
+		}
+		if rest, ok := strings.CutPrefix(body, "p{"); ok {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return 0, "", fmt.Errorf("unterminated \\p{ in character class %s", text)
+			}
+			body = rest[end+1:]
+			return 0, rest[:end], nil
+		}
+		var seq string
+		seq, body = splitEscape(body)
+		switch seq {
+		case "]", "[", "^", "-":
+			return rune(seq[0]), "", nil
+		}
+		// byte escapes like \xe9 stand for code points in classes
+		r, _, err := unescape(seq)
+		if err != nil {
+			return 0, "", fmt.Errorf("unknown escape sequence in character class: \\%s", seq)
+		}
+		return r, "", nil
+	}
+
+	for body != "" {
+		lo, category, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if category != "" {
+			c.Categories = append(c.Categories, category)
+			continue
+		}
+		hi := lo
+		if len(body) > 1 && body[0] == '-' {
+			body = body[1:]
+			if hi, category, err = next(); err != nil {
+				return nil, err
+			}
+			if category != "" {
+				return nil, fmt.Errorf("invalid range to \\p{%s} in character class %s", category, text)
+			}
+		}
+		c.Ranges = append(c.Ranges, RuneRange{Lo: lo, Hi: hi})
+	}
+	return c, nil
+}
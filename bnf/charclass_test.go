@@ -0,0 +1,85 @@
+package bnf_test
+
+import (
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCharClass_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		match   []string
+		reject  []string
+	}{
+		{`<c> ::= 'a'..'z'`, []string{"a", "q", "z"}, []string{"A", "", "ab", "{"}},
+		{`<c> ::= "α".."ω"`, []string{"α", "λ"}, []string{"a", "Ω"}},
+		{`<c> ::= [a-zA-Z_]`, []string{"a", "Z", "_"}, []string{"0", "-"}},
+		{`<c> ::= [^"\n]`, []string{"a", "'", "ż"}, []string{`"`, "\n", ""}},
+		{`<c> ::= [\-\]\\^]`, []string{"-", "]", `\`, "^"}, []string{"a"}},
+		{`<c> ::= [a-]`, []string{"a", "-"}, []string{"b"}},
+		{`<c> ::= \p{L}`, []string{"a", "ż", "Ω", "字"}, []string{"1", " "}},
+		{`<c> ::= \P{L}`, []string{"1", " "}, []string{"a"}},
+		{`<c> ::= [\p{Lu}0-9]`, []string{"A", "Ż", "5"}, []string{"a"}},
+		{`<c> ::= \p{Greek}+`, []string{"αβγ"}, []string{"abc"}},
+	}
+	for _, tt := range tests {
+		g, err := bnf.LoadGrammarString(tt.grammar)
+		if !assert.NoError(t, err, tt.grammar) {
+			continue
+		}
+		for _, in := range tt.match {
+			ok, err := g.Match(in)
+			assert.True(t, ok, "%s on %q", tt.grammar, in)
+			assert.NoError(t, err)
+		}
+		for _, in := range tt.reject {
+			ok, _ := g.Match(in)
+			assert.False(t, ok, "%s on %q", tt.grammar, in)
+		}
+	}
+}
+
+func TestCharClass_Expect(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<id> ::= 'a'..'z' [^"\n]`)
+	assert.NoError(t, err)
+
+	_, err = g.Parse("1")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, []string{"[a-z]"}, perr.Expected)
+	}
+	_, err = g.Parse("a\n")
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, []string{`[^"\n]`}, perr.Expected)
+	}
+
+	tree, err := g.Parse("ab")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", tree.Children[0].Value)
+}
+
+func TestCharClass_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range []string{
+		`<c> ::= 'z'..'a'`,
+		`<c> ::= 'ab'..'z'`,
+		`<c> ::= 'a'..`,
+		`<c> ::= [z-a]`,
+		`<c> ::= []`,
+		`<c> ::= [abc`,
+		`<c> ::= \p{Klingon}`,
+		`<c> ::= [\q]`,
+		`<c> ::= \x`,
+	} {
+		_, err := bnf.LoadGrammarString(src)
+		assert.Error(t, err, src)
+	}
+}
@@ -6,12 +6,12 @@ type choice struct {
 
 func (c *choice) match(ctx *context, pos int) ([]MatchResult, error) {
 	var results []MatchResult
-	for _, opt := range c.Options {
+	for i, opt := range c.Options {
 		matches, err := ctx.Match(opt, pos)
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, matches...)
+		results = append(results, ctx.traced(matches, coverKey{node: c, branch: i})...)
 	}
 	return results, nil
 }
@@ -12,6 +12,7 @@ type memoKey struct {
 type memoEntry struct {
 	results         []MatchResult // remember results
 	isLeftRecursive bool          // detect left-sided recursion
+	quiet           bool          // computed in a lookahead, without recording failures
 }
 
 type context struct {
@@ -28,6 +29,19 @@ type context struct {
 	// call stack
 	stack []string
 
+	// nesting of lookaheads, whose failures are not recorded
+	quiet int
+
+	// tokens of grammars with @token definitions by position, scanned on demand
+	tokens map[*scanner]map[int]scanned
+
+	// coverage
+	trace bool // record the coverage points exercised by every result
+
+	// semantic actions, run on every rule match while evaluating
+	actions map[string]Action
+	values  map[*ASTNode]evaluated
+
 	// limits
 	maxGrowthIterations int // maximum iterations for left-recursion growth
 	matchCounter        int // total match attempts (for detecting pathological grammars)
@@ -65,7 +79,8 @@ func (ctx *context) Match(node node, pos int) ([]MatchResult, error) {
 	// Memoization is key to Packrat parsing. We cache results for each (node, pos) pair
 	// to ensure linear time complexity (for non-recursive grammars) and to handle recursion.
 	key := memoKey{node: node, pos: pos}
-	if entry, ok := ctx.memo[key]; ok {
+	// a result of a lookahead is matched again outside of one, for its failures
+	if entry, ok := ctx.memo[key]; ok && (!entry.quiet || ctx.quiet > 0) {
 		if entry.isLeftRecursive {
 			// CRITICAL: Left Recursion Handling.
 			// When we hit a rule that is already being evaluated at this position,
@@ -136,7 +151,7 @@ func (ctx *context) Match(node node, pos int) ([]MatchResult, error) {
 		delete(ctx.memo, key)
 	} else {
 		// No active recursion stack at this position, so this result is final and safe to cache.
-		ctx.memo[key] = &memoEntry{isLeftRecursive: false, results: currentResults}
+		ctx.memo[key] = &memoEntry{isLeftRecursive: false, results: currentResults, quiet: ctx.quiet > 0}
 	}
 
 	// 6. Error Tracking
@@ -157,6 +172,73 @@ func (ctx *context) Match(node node, pos int) ([]MatchResult, error) {
 	return currentResults, nil
 }
 
+// lookahead matches n at pos without recording its failures, which predicates
+// and differences hope for rather than report.
+func (ctx *context) lookahead(n node, pos int) ([]MatchResult, error) {
+	farthest, perr := ctx.FarthestPos, ctx.error
+	var saved ParseError
+	if perr != nil {
+		saved = *perr // merging expectations updates the error in place
+	}
+	ctx.quiet++
+	matches, err := ctx.Match(n, pos)
+	ctx.quiet--
+	ctx.FarthestPos, ctx.error = farthest, perr
+	if perr != nil {
+		*perr = saved
+	}
+	return matches, err
+}
+
+// trace is a derivation trace, shared by every result built on it so that
+// extending a trace never copies it: a coverage point followed by rest, or
+// with join set the points of join followed by rest.
+type trace struct {
+	key  coverKey
+	join *trace
+	rest *trace
+}
+
+// each calls f for every point of the trace.
+func (t *trace) each(f func(coverKey)) {
+	for stack := []*trace{t}; len(stack) > 0; {
+		t := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if t == nil {
+			continue
+		}
+		if t.join == nil {
+			f(t.key)
+		}
+		stack = append(stack, t.rest, t.join)
+	}
+}
+
+// traced prepends the coverage point k to the derivation trace of every result.
+// It is a no-op unless the context traces coverage.
+func (ctx *context) traced(results []MatchResult, k coverKey) []MatchResult {
+	if !ctx.trace {
+		return results
+	}
+	out := make([]MatchResult, len(results))
+	for i, r := range results {
+		out[i] = r
+		out[i].hits = &trace{key: k, rest: r.hits}
+	}
+	return out
+}
+
+// joinHits concatenates the derivation traces of two consecutive matches.
+func (ctx *context) joinHits(a, b *trace) *trace {
+	switch {
+	case !ctx.trace || a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	return &trace{join: a, rest: b}
+}
+
 func resultsEndEqual(a, b []MatchResult) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,413 @@
+package bnf
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Kinds of coverage points.
+const (
+	CoverRule        = "rule"        // the rule was expanded
+	CoverAlternative = "alternative" // an alternative of a choice was taken
+	CoverRepeat      = "repeat"      // a repetition ran 0, 1 or many times
+)
+
+// CoveragePoint identifies a grammar element a test corpus should exercise.
+type CoveragePoint struct {
+	Rule   string // rule containing the element
+	Kind   string // CoverRule, CoverAlternative or CoverRepeat
+	Index  int    // ordinal of the choice or repetition within the rule, counted from 1
+	Branch int    // alternative index for choices, iteration class (0, 1, 2 = many) for repetitions
+	Expr   string // BNF text of the alternative or repetition
+}
+
+// String returns a short human-readable description of the point.
+func (p CoveragePoint) String() string {
+	switch p.Kind {
+	case CoverAlternative:
+		return fmt.Sprintf("%s: choice #%d alternative %d: %s", p.Rule, p.Index, p.Branch+1, p.Expr)
+	case CoverRepeat:
+		times := [...]string{"0 times", "once", "many times"}[p.Branch]
+		return fmt.Sprintf("%s: repetition #%d %s: %s", p.Rule, p.Index, times, p.Expr)
+	}
+	return p.Rule
+}
+
+// coverKey identifies a coverage point during matching or generation: rule points
+// by name, choices and repetitions by their node and branch.
+type coverKey struct {
+	rule   string
+	node   node
+	branch int
+}
+
+type point struct {
+	CoveragePoint
+	key  coverKey
+	path []node // nodes from the rule expression down to the element
+}
+
+// CoveragePoints lists every rule, choice alternative and repetition class of the
+// grammar in definition order.
+func (g *Grammar) CoveragePoints() []CoveragePoint {
+	var out []CoveragePoint
+	for _, p := range g.points() {
+		out = append(out, p.CoveragePoint)
+	}
+	return out
+}
+
+func (g *Grammar) points() []point {
+	var out []point
+	for _, name := range g.ruleNames() {
+		rule := g.Rules[name]
+		out = append(out, point{
+			CoveragePoint: CoveragePoint{Rule: name, Kind: CoverRule, Expr: formatNode(rule.Expr)},
+			key:           coverKey{rule: name},
+		})
+
+		index := 0
+		var walk func(n node, path []node)
+		walk = func(n node, path []node) {
+			path = append(path[:len(path):len(path)], n)
+			add := func(kind string, branch int, expr string) {
+				out = append(out, point{
+					CoveragePoint: CoveragePoint{Rule: name, Kind: kind, Index: index, Branch: branch, Expr: expr},
+					key:           coverKey{node: n, branch: branch},
+					path:          path,
+				})
+			}
+			switch t := n.(type) {
+			case *sequence:
+				for _, e := range t.Elements {
+					walk(e, path)
+				}
+			case *choice:
+				index++
+				for i, o := range t.Options {
+					add(CoverAlternative, i, formatNode(o))
+				}
+				for _, o := range t.Options {
+					walk(o, path)
+				}
+			case *repeat:
+				index++
+				for class := min(t.Min, 2); class <= 2; class++ {
+					add(CoverRepeat, class, formatNode(t))
+				}
+				walk(t.Node, path)
+			case *optional:
+				index++
+				add(CoverRepeat, 0, formatNode(t))
+				add(CoverRepeat, 1, formatNode(t))
+				walk(t.Node, path)
+			case *difference:
+				walk(t.Node, path)
+			case *dropped:
+				walk(t.Node, path)
+			case *token:
+				walk(t.def.node, path)
+			}
+		}
+		walk(rule.Expr, nil)
+	}
+	return out
+}
+
+// CorpusCase is a generated input together with the coverage points it exercised first.
+type CorpusCase struct {
+	Input  string
+	Covers []CoveragePoint
+}
+
+// CoverageCorpus generates a small set of inputs which together exercise every
+// rule reachable from the start rule, every alternative of every choice and
+// every repetition at zero, one and many iterations. Each case is the shortest
+// derivation steered through one not yet covered point, so cases stay small
+// and few. Points that cannot be exercised are returned as uncovered.
+func (g *Grammar) CoverageCorpus() ([]CorpusCase, []CoveragePoint, error) {
+	gen, err := g.newGenerator(rand.New(rand.NewPCG(1, 2)), GenerateOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	gen.opts.MaxDepth = 0 // always take the shortest derivation unless steered
+	gen.hits = map[coverKey]bool{}
+	gen.covered = map[coverKey]bool{}
+
+	routes := g.ruleRoutes(gen.opts.Start)
+	covered := gen.covered
+	var cases []CorpusCase
+	var uncovered []CoveragePoint
+
+	pts := g.points()
+	for _, p := range pts {
+		if covered[p.key] {
+			continue
+		}
+		route, reachable := routes[p.Rule]
+		if !reachable || !gen.steer(route, p) {
+			uncovered = append(uncovered, p.CoveragePoint)
+			continue
+		}
+
+		s, err := gen.sentence()
+		if err != nil || !gen.hits[p.key] {
+			uncovered = append(uncovered, p.CoveragePoint)
+			continue
+		}
+
+		c := CorpusCase{Input: s}
+		for _, q := range pts {
+			if gen.hits[q.key] && !covered[q.key] {
+				covered[q.key] = true
+				c.Covers = append(c.Covers, q.CoveragePoint)
+			}
+		}
+		cases = append(cases, c)
+	}
+	return cases, uncovered, nil
+}
+
+// steer plans the one-shot decisions leading the next sentence through route
+// into the point's rule and then to the point itself.
+func (gen *generator) steer(route [][]node, p point) bool {
+	gen.force = map[node]int{}
+	for _, path := range route {
+		gen.planPath(path)
+	}
+	gen.planPath(p.path)
+
+	switch t := p.key.node.(type) {
+	case *choice:
+		if nodeCost(t.Options[p.Branch], gen.cost) >= infCost {
+			return false
+		}
+		gen.force[t] = p.Branch
+	case *repeat:
+		if p.Branch > 0 && nodeCost(t.Node, gen.cost) >= infCost {
+			return false
+		}
+		gen.force[t] = max(p.Branch, t.Min)
+	case *optional:
+		if p.Branch > 0 && nodeCost(t.Node, gen.cost) >= infCost {
+			return false
+		}
+		gen.force[t] = p.Branch
+	}
+	return true
+}
+
+// planPath forces the decisions needed to reach the last node of path from its first.
+func (gen *generator) planPath(path []node) {
+	for i := 0; i+1 < len(path); i++ {
+		switch t := path[i].(type) {
+		case *choice:
+			for j, o := range t.Options {
+				if o == path[i+1] {
+					gen.force[t] = j
+				}
+			}
+		case *repeat:
+			gen.force[t] = max(1, t.Min)
+		case *optional:
+			gen.force[t] = 1
+		}
+	}
+}
+
+// ruleRoutes finds for every rule reachable from start the shortest chain of rule
+// references leading to it, each given as the node path within the referencing rule.
+func (g *Grammar) ruleRoutes(start string) map[string][][]node {
+	routes := map[string][][]node{start: nil}
+	queue := []string{start}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		rule := g.Rules[name]
+		if rule == nil {
+			continue
+		}
+
+		var walk func(n node, path []node)
+		walk = func(n node, path []node) {
+			path = append(path[:len(path):len(path)], n)
+			switch t := n.(type) {
+			case *nonTerminal:
+				if _, seen := routes[t.Name]; !seen {
+					routes[t.Name] = append(routes[name][:len(routes[name]):len(routes[name])], path)
+					queue = append(queue, t.Name)
+				}
+			case *sequence:
+				for _, e := range t.Elements {
+					walk(e, path)
+				}
+			case *choice:
+				for _, o := range t.Options {
+					walk(o, path)
+				}
+			case *repeat:
+				walk(t.Node, path)
+			case *optional:
+				walk(t.Node, path)
+			case *difference:
+				walk(t.Node, path)
+			case *dropped:
+				walk(t.Node, path)
+			case *token:
+				walk(t.def.node, path)
+			}
+		}
+		walk(rule.Expr, nil)
+	}
+	return routes
+}
+
+// formatNode renders an execution node back into BNF notation.
+func formatNode(n node) string {
+	switch t := n.(type) {
+	case *terminal:
+		return fmt.Sprintf("%q", t.Value)
+	case *caselessTerminal:
+		return fmt.Sprintf("%qi", t.Value)
+	case *Regex:
+		return "/" + strings.ReplaceAll(t.Re.String(), "/", `\/`) + "/"
+	case *charClass:
+		return t.String()
+	case *anyRune:
+		return "."
+	case *endOfInput:
+		return "EOF"
+	case *notPredicate:
+		return "!" + formatOperand(t.Node)
+	case *difference:
+		return formatOperand(t.Node) + " - " + formatOperand(t.Except)
+	case *dropped:
+		return formatNode(t.Node)
+	case *skipper:
+		return formatNode(t.Node)
+	case *token:
+		return formatNode(t.def.node)
+	case regexer:
+		return formatNode(t.regex())
+	case *nonTerminal:
+		return "<" + t.Name + ">"
+	case *sequence:
+		parts := make([]string, len(t.Elements))
+		for i, e := range t.Elements {
+			parts[i] = formatNode(e)
+			if _, ok := e.(*choice); ok {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+		return strings.Join(parts, " ")
+	case *choice:
+		parts := make([]string, len(t.Options))
+		for i, o := range t.Options {
+			parts[i] = formatNode(o)
+		}
+		return strings.Join(parts, " | ")
+	case *repeat:
+		op := "*"
+		if t.Min > 0 {
+			op = "+"
+		}
+		return formatOperand(t.Node) + op
+	case *optional:
+		return formatOperand(t.Node) + "?"
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+func formatOperand(n node) string {
+	switch n.(type) {
+	case *sequence, *choice, *notPredicate, *difference:
+		return "(" + formatNode(n) + ")"
+	}
+	return formatNode(n)
+}
+
+// PointCoverage is a coverage point with the number of successful parses exercising it.
+type PointCoverage struct {
+	CoveragePoint
+	Hits int
+}
+
+// Coverage records which rules, alternatives and repetition classes the successful
+// parses of a test corpus exercised, similar to `go test -cover` for grammars.
+type Coverage struct {
+	Inputs  int // inputs checked
+	Matched int // inputs matching the grammar
+
+	g      *Grammar
+	points []point
+	hits   map[coverKey]int
+}
+
+// NewCoverage creates an empty coverage record for the grammar.
+func NewCoverage(g *Grammar) *Coverage {
+	return &Coverage{
+		g:      g,
+		points: g.points(),
+		hits:   map[coverKey]int{},
+	}
+}
+
+// Match checks the input like Grammar.Match and, when it matches, records the
+// points exercised by its derivation. For ambiguous input the first derivation counts.
+func (c *Coverage) Match(input string) (bool, error) {
+	c.Inputs++
+	rule, ok := c.g.Rules[c.g.Start]
+	if !ok {
+		return false, fmt.Errorf("unknown start rule: %s", c.g.Start)
+	}
+
+	ctx := NewContext(input)
+	ctx.trace = true
+	matches, err := ctx.Match(c.g.top(rule), 0)
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range matches {
+		if m.End == len(input) {
+			c.Matched++
+			// the start rule is matched through top, counted here once
+			start := coverKey{rule: c.g.Start}
+			c.hits[start]++
+			seen := map[coverKey]bool{start: true}
+			m.hits.each(func(k coverKey) {
+				if !seen[k] {
+					seen[k] = true
+					c.hits[k]++
+				}
+			})
+			return true, nil
+		}
+	}
+
+	return false, ctx.error
+}
+
+// Report returns every coverage point of the grammar with its hit count, in definition order.
+func (c *Coverage) Report() []PointCoverage {
+	out := make([]PointCoverage, len(c.points))
+	for i, p := range c.points {
+		out[i] = PointCoverage{CoveragePoint: p.CoveragePoint, Hits: c.hits[p.key]}
+	}
+	return out
+}
+
+// Percent returns the share of coverage points exercised at least once.
+func (c *Coverage) Percent() float64 {
+	if len(c.points) == 0 {
+		return 100
+	}
+	covered := 0
+	for _, p := range c.points {
+		if c.hits[p.key] > 0 {
+			covered++
+		}
+	}
+	return 100 * float64(covered) / float64(len(c.points))
+}
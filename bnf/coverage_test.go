@@ -0,0 +1,151 @@
+package bnf_test
+
+import (
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoveragePoints(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<S> ::= <A>+ ("x" | "y")?
+<A> ::= "a"
+`)
+	assert.NoError(t, err)
+
+	var got []string
+	for _, p := range g.CoveragePoints() {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		"S",
+		`S: repetition #1 once: <A>+`,
+		`S: repetition #1 many times: <A>+`,
+		`S: repetition #2 0 times: ("x" | "y")?`,
+		`S: repetition #2 once: ("x" | "y")?`,
+		`S: choice #3 alternative 1: "x"`,
+		`S: choice #3 alternative 2: "y"`,
+		"A",
+	}, got)
+}
+
+func TestCoverageCorpus(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"numbers", "hour", "postal", "left-recursive"} {
+		t.Run(file, func(t *testing.T) {
+			g, err := bnf.LoadGrammarFile("../examples/" + file + ".bnf")
+			assert.NoError(t, err)
+
+			cases, uncovered, err := g.CoverageCorpus()
+			assert.NoError(t, err)
+			assert.Empty(t, uncovered)
+			assert.NotEmpty(t, cases)
+
+			// every point is claimed by exactly one case
+			claimed := 0
+			for _, c := range cases {
+				assert.NotEmpty(t, c.Covers)
+				claimed += len(c.Covers)
+
+				ok, err := g.Match(c.Input)
+				assert.True(t, ok, c.Input)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, len(g.CoveragePoints()), claimed)
+		})
+	}
+}
+
+func TestCoverageCorpus_Unreachable(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<S>      ::= "a" | "b" <loop>
+<loop>   ::= "c" <loop>
+<unused> ::= "d"
+`)
+	assert.NoError(t, err)
+
+	cases, uncovered, err := g.CoverageCorpus()
+	assert.NoError(t, err)
+	assert.Len(t, cases, 1)
+	assert.Equal(t, "a", cases[0].Input)
+
+	var names []string
+	for _, p := range uncovered {
+		names = append(names, p.String())
+	}
+	assert.Equal(t, []string{`S: choice #1 alternative 2: "b" <loop>`, "loop", "unused"}, names)
+}
+
+func TestCoverage_Report(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+
+	cov := bnf.NewCoverage(g)
+	for _, in := range []string{"4pm", "7:38pm", "x"} {
+		cov.Match(in)
+	}
+	assert.Equal(t, 3, cov.Inputs)
+	assert.Equal(t, 2, cov.Matched)
+
+	hits := map[string]int{}
+	for _, p := range cov.Report() {
+		hits[p.String()] = p.Hits
+	}
+	assert.Equal(t, 2, hits["time"])
+	assert.Equal(t, 1, hits[`time: repetition #1 0 times: (":" <minute>)?`])
+	assert.Equal(t, 1, hits[`time: repetition #1 once: (":" <minute>)?`])
+	assert.Equal(t, 2, hits[`ampm: choice #1 alternative 2: "pm"`])
+	assert.Equal(t, 0, hits[`ampm: choice #1 alternative 1: "am"`])
+	assert.Equal(t, 1, hits[`digit: choice #1 alternative 9: "8"`])
+	assert.Equal(t, 0, hits[`hour: repetition #1 once: <digit>?`])
+	assert.Equal(t, 0, hits[`digit: choice #1 alternative 1: "0"`])
+
+	assert.InDelta(t, 100*14.0/23.0, cov.Percent(), 0.001)
+}
+
+func TestCoverage_RecursiveStart(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`list ::= "a" list | "a"`)
+	assert.NoError(t, err)
+
+	cov := bnf.NewCoverage(g)
+	for _, in := range []string{"a", "aaa"} {
+		ok, err := cov.Match(in)
+		assert.True(t, ok, in)
+		assert.NoError(t, err)
+	}
+	hits := map[string]int{}
+	for _, p := range cov.Report() {
+		hits[p.String()] = p.Hits
+	}
+	// once per input, however deep list recurses
+	assert.Equal(t, 2, hits["list"])
+}
+
+func TestCoverage_CorpusIsComplete(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/postal.bnf")
+	assert.NoError(t, err)
+
+	cases, _, err := g.CoverageCorpus()
+	assert.NoError(t, err)
+
+	cov := bnf.NewCoverage(g)
+	for _, c := range cases {
+		ok, err := cov.Match(c.Input)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 100.0, cov.Percent())
+}
@@ -0,0 +1,42 @@
+package bnf
+
+import "strings"
+
+// difference matches what Node matches, except the spans Except matches as a
+// whole, written as A - B in grammars.
+type difference struct {
+	Node   node
+	Except node
+}
+
+func (d *difference) match(ctx *context, pos int) ([]MatchResult, error) {
+	matches, err := ctx.Match(d.Node, pos)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	excluded, err := ctx.lookahead(d.Except, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	ends := map[int]bool{}
+	for _, m := range excluded {
+		ends[m.End] = true
+	}
+	var results []MatchResult
+	for _, m := range matches {
+		if !ends[m.End] {
+			results = append(results, m)
+		}
+	}
+	return results, nil
+}
+
+func (d *difference) Expect() []string {
+	except := strings.Join(d.Except.Expect(), " or ")
+	var out []string
+	for _, e := range d.Node.Expect() {
+		out = append(out, e+" except "+except)
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package bnf_test
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDifference_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		match   []string
+		reject  []string
+	}{
+		{`<id> ::= [a-z]+ - ("if" | "else")`, []string{"iff", "els", "x"}, []string{"if", "else"}},
+		{`<s> ::= "\"" (<char> - "\"")* "\""` + "\n" + `<char> ::= .`, []string{`""`, `"a'b"`}, []string{`"a"b"`}},
+		{`<s> ::= <id> - <kw> - "x"` + "\n" + `<id> ::= [a-z]+` + "\n" + `<kw> ::= "for"`, []string{"fo", "forx"}, []string{"for", "x"}},
+		{`<s> ::= [a-z]+ - "ab" "c"`, []string{"ac", "abcc"}, []string{"abc"}},
+		{`<s> ::= [a-z]+-"if"`, []string{"abc", "iff"}, []string{"if"}},
+		{`<s> ::= "a" (-"b") [a-z] - "d"`, []string{"abc"}, []string{"abd", "ab"}},
+	}
+	for _, tt := range tests {
+		g, err := bnf.LoadGrammarString(tt.grammar)
+		if !assert.NoError(t, err, tt.grammar) {
+			continue
+		}
+		for _, in := range tt.match {
+			ok, err := g.Match(in)
+			assert.True(t, ok, "%s on %q", tt.grammar, in)
+			assert.NoError(t, err)
+		}
+		for _, in := range tt.reject {
+			ok, _ := g.Match(in)
+			assert.False(t, ok, "%s on %q", tt.grammar, in)
+		}
+	}
+}
+
+func TestDifference_Expect(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<assign>  ::= <name> "="
+<name>    ::= <ident> - <keyword>
+<ident>   ::= [a-z]+
+<keyword> ::= "if" | "for"
+`)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("fort=")
+	assert.NoError(t, err)
+	assert.Equal(t, "ident", tree.Children[0].Children[0].Type)
+
+	_, err = g.Parse("1=")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 0, perr.Pos)
+		assert.Contains(t, perr.Expected, "ident except keyword")
+	}
+}
+
+func TestDifference_Generate(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<s> ::= ([a-c] [a-c]?) - ("a" | "bb" | "c") ","`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		s, err := g.Generate(r, bnf.GenerateOptions{})
+		if !assert.NoError(t, err) {
+			break
+		}
+		ok, _ := g.Match(s)
+		assert.True(t, ok, "%q", s)
+	}
+
+	out, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b,"}, out)
+
+	g, err = bnf.LoadGrammarString(`<s> ::= "a" - "a"`)
+	assert.NoError(t, err)
+	_, err = g.Generate(r, bnf.GenerateOptions{})
+	assert.ErrorContains(t, err, `cannot generate text not matching "a"`)
+}
+
+func TestDifference_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `name ::= ident - keyword - "_" ("," ident - (keyword | "x"))*` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(`name ::= ident - keyword ":"` + "\n" + `ident ::= "a"` + "\n" + `keyword ::= "b"`))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	want := map[string]string{
+		bnf.ExportISO:  `name    = (ident - keyword), ":" ;`,
+		bnf.ExportW3C:  `name    ::= (ident - keyword) ":"`,
+		bnf.ExportABNF: `name    = (ident <except keyword>) ":"`,
+		bnf.ExportPEG:  `name    <- (!keyword ident) ":"`,
+	}
+	for format, line := range want {
+		var buf bytes.Buffer
+		warnings, err := bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: format})
+		assert.NoError(t, err)
+		assert.Equal(t, line, strings.Split(buf.String(), "\n")[0], format)
+		if format == bnf.ExportABNF {
+			assert.Contains(t, warnings, "rule <name>: difference cannot be expressed in ABNF")
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package bnf
+
+import (
+	"cmp"
+	"fmt"
+	"regexp/syntax"
+	"slices"
+	"unicode/utf8"
+)
+
+// EnumerateOptions bounds the part of the language listed by Grammar.Enumerate.
+type EnumerateOptions struct {
+	Start    string // rule to enumerate, defaults to the grammar start rule
+	MaxLen   int    // maximum sentence length in characters (0 = no limit, requires MaxDepth)
+	MaxDepth int    // maximum rule nesting of a derivation (0 = no limit, requires MaxLen)
+	MaxCount int    // maximum number of sentences returned (0 = no limit)
+	MaxSet   int    // maximum size of any intermediate set before giving up (default 1000000)
+}
+
+type langSet map[string]struct{}
+
+type enumerator struct {
+	opts  EnumerateOptions
+	rules map[string]langSet
+	regex map[*Regex]langSet
+}
+
+// Enumerate lists every sentence of the language within the given bounds,
+// deduplicated and in shortlex order (shorter first, then lexicographically).
+// Character classes of regex terminals are narrowed to their printable ASCII
+// members when they have any. Every candidate is confirmed with MatchFrom.
+func (g *Grammar) Enumerate(opts EnumerateOptions) ([]string, error) {
+	if opts.Start == "" {
+		opts.Start = g.Start
+	}
+	if _, ok := g.Rules[opts.Start]; !ok {
+		return nil, fmt.Errorf("unknown start rule: %s", opts.Start)
+	}
+	if opts.MaxLen <= 0 && opts.MaxDepth <= 0 {
+		return nil, fmt.Errorf("enumeration needs a length or depth bound")
+	}
+	if opts.MaxSet <= 0 {
+		opts.MaxSet = 1000000
+	}
+
+	e := &enumerator{
+		opts:  opts,
+		rules: make(map[string]langSet, len(g.Rules)),
+		regex: map[*Regex]langSet{},
+	}
+	for name := range g.Rules {
+		e.rules[name] = langSet{}
+	}
+
+	// Every round extends derivations by one level of rule nesting, all rules
+	// are recomputed from the previous round until nothing new shows up.
+	for depth := 1; opts.MaxDepth <= 0 || depth <= opts.MaxDepth; depth++ {
+		next := make(map[string]langSet, len(g.Rules))
+		changed := false
+		for name, r := range g.Rules {
+			set, err := e.node(r.Expr)
+			if err != nil {
+				return nil, err
+			}
+			if len(set) != len(e.rules[name]) {
+				changed = true
+			}
+			next[name] = set
+		}
+		e.rules = next
+		if !changed {
+			break
+		}
+	}
+
+	var out []string
+	for s := range e.rules[opts.Start] {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, shortlex)
+
+	var accepted []string
+	for _, s := range out {
+		if opts.MaxCount > 0 && len(accepted) >= opts.MaxCount {
+			break
+		}
+		ok, err := g.MatchFrom(opts.Start, s)
+		if ok {
+			accepted = append(accepted, s)
+		} else if _, isParseErr := err.(*ParseError); err != nil && !isParseErr {
+			return nil, err
+		}
+	}
+	return accepted, nil
+}
+
+func shortlex(a, b string) int {
+	if c := cmp.Compare(utf8.RuneCountInString(a), utf8.RuneCountInString(b)); c != 0 {
+		return c
+	}
+	return cmp.Compare(a, b)
+}
+
+func (e *enumerator) fits(s string) bool {
+	return e.opts.MaxLen <= 0 || utf8.RuneCountInString(s) <= e.opts.MaxLen
+}
+
+func (e *enumerator) check(set langSet) error {
+	if len(set) > e.opts.MaxSet {
+		return fmt.Errorf("enumeration exceeded %d sentences, use tighter bounds", e.opts.MaxSet)
+	}
+	return nil
+}
+
+func (e *enumerator) node(n node) (langSet, error) {
+	switch t := n.(type) {
+	case *terminal:
+		return e.literal(t.Value), nil
+	case *caselessTerminal:
+		// only the spelling of the grammar, not every mix of cases
+		return e.literal(t.Value), nil
+	case *Regex:
+		return e.regexp(t)
+	case regexer:
+		return e.regexp(t.regex())
+	case *endOfInput, *notPredicate:
+		// zero-width, the lookahead is checked when candidates are confirmed
+		return langSet{"": {}}, nil
+	case *difference:
+		// like lookaheads, the exception is checked when candidates are confirmed
+		return e.node(t.Node)
+	case *dropped:
+		return e.node(t.Node)
+	case *token:
+		// text scanned as another token is dropped when candidates are confirmed
+		return e.node(t.def.node)
+	case *nonTerminal:
+		if t.Rule == nil {
+			return nil, fmt.Errorf("NonTerminal without Rule: %s", t.Name)
+		}
+		return e.rules[t.Name], nil
+	case *sequence:
+		skip, err := e.skip(t.Skip)
+		if err != nil {
+			return nil, err
+		}
+		out := langSet{"": {}}
+		for i, el := range t.Elements {
+			set, err := e.node(el)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 && skip != nil {
+				if set, err = e.concat(skip, set); err != nil {
+					return nil, err
+				}
+			}
+			if out, err = e.concat(out, set); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case *skipper:
+		set, err := e.node(t.Node)
+		if err != nil {
+			return nil, err
+		}
+		return e.repeat(set, 0, 1, nil)
+	case *choice:
+		out := langSet{}
+		for _, o := range t.Options {
+			set, err := e.node(o)
+			if err != nil {
+				return nil, err
+			}
+			for s := range set {
+				out[s] = struct{}{}
+			}
+		}
+		return out, e.check(out)
+	case *repeat:
+		set, err := e.node(t.Node)
+		if err != nil {
+			return nil, err
+		}
+		skip, err := e.skip(t.Skip)
+		if err != nil {
+			return nil, err
+		}
+		return e.repeat(set, t.Min, -1, skip)
+	case *optional:
+		set, err := e.node(t.Node)
+		if err != nil {
+			return nil, err
+		}
+		return e.repeat(set, 0, 1, nil)
+	}
+	return nil, fmt.Errorf("cannot enumerate node %T", n)
+}
+
+func (e *enumerator) literal(s string) langSet {
+	if !e.fits(s) {
+		return langSet{}
+	}
+	return langSet{s: {}}
+}
+
+func (e *enumerator) concat(a, b langSet) (langSet, error) {
+	out := langSet{}
+	for x := range a {
+		for y := range b {
+			if s := x + y; e.fits(s) {
+				out[s] = struct{}{}
+			}
+		}
+	}
+	return out, e.check(out)
+}
+
+// skip returns the texts skipped between elements of syntactic rules, nil
+// without a skipper.
+func (e *enumerator) skip(n node) (langSet, error) {
+	if n == nil {
+		return nil, nil
+	}
+	return e.node(n)
+}
+
+// repeat computes the closure of set with lo to hi iterations (hi < 0 = unbounded),
+// with texts of sep between them when not nil. Like the matcher, only iterations
+// consuming input count.
+func (e *enumerator) repeat(set langSet, lo, hi int, sep langSet) (langSet, error) {
+	step := langSet{}
+	for s := range set {
+		if s != "" {
+			step[s] = struct{}{}
+		}
+	}
+	if hi < 0 {
+		hi = e.opts.MaxLen
+		if hi <= 0 {
+			hi = e.opts.MaxDepth
+		}
+	}
+
+	out := langSet{}
+	cur := langSet{"": {}}
+	for i := 0; i <= hi && len(cur) > 0; i++ {
+		if i >= lo {
+			for s := range cur {
+				out[s] = struct{}{}
+			}
+		}
+		next := step
+		if i > 0 && sep != nil {
+			var err error
+			if next, err = e.concat(sep, step); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if cur, err = e.concat(cur, next); err != nil {
+			return nil, err
+		}
+	}
+	return out, e.check(out)
+}
+
+func (e *enumerator) regexp(r *Regex) (langSet, error) {
+	if set, ok := e.regex[r]; ok {
+		return set, nil
+	}
+	re, err := syntax.Parse(r.Re.String(), syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %q: %w", r.Re.String(), err)
+	}
+	set, err := e.syntax(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	e.regex[r] = set
+	return set, nil
+}
+
+func (e *enumerator) syntax(re *syntax.Regexp) (langSet, error) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return e.literal(string(re.Rune)), nil
+	case syntax.OpCharClass:
+		return e.class(re.Rune)
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return e.class([]rune{' ', '~'})
+	case syntax.OpCapture:
+		return e.syntax(re.Sub[0])
+	case syntax.OpConcat:
+		out := langSet{"": {}}
+		for _, sub := range re.Sub {
+			set, err := e.syntax(sub)
+			if err != nil {
+				return nil, err
+			}
+			if out, err = e.concat(out, set); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case syntax.OpAlternate:
+		out := langSet{}
+		for _, sub := range re.Sub {
+			set, err := e.syntax(sub)
+			if err != nil {
+				return nil, err
+			}
+			for s := range set {
+				out[s] = struct{}{}
+			}
+		}
+		return out, e.check(out)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		set, err := e.syntax(re.Sub[0])
+		if err != nil {
+			return nil, err
+		}
+		switch re.Op {
+		case syntax.OpStar:
+			return e.repeat(set, 0, -1, nil)
+		case syntax.OpPlus:
+			return e.repeat(set, 1, -1, nil)
+		case syntax.OpQuest:
+			return e.repeat(set, 0, 1, nil)
+		}
+		return e.repeat(set, re.Min, re.Max, nil)
+	}
+	// anchors, word boundaries and empty matches produce no text
+	return langSet{"": {}}, nil
+}
+
+// class expands a regexp character class given as [lo, hi] pairs.
+func (e *enumerator) class(ranges []rune) (langSet, error) {
+	out := langSet{}
+	for i := 0; i < len(ranges); i += 2 {
+		for c := max(ranges[i], ' '); c <= min(ranges[i+1], '~'); c++ {
+			out[string(c)] = struct{}{}
+		}
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		for c := ranges[i]; c <= ranges[i+1]; c++ {
+			out[string(c)] = struct{}{}
+		}
+		if err := e.check(out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package bnf_test
+
+import (
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumerate_Shortlex(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<list> ::= <list> "," <id> | <id>
+<id>   ::= "a" | "b"
+`)
+	assert.NoError(t, err)
+
+	got, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "a,a", "a,b", "b,a", "b,b"}, got)
+}
+
+func TestEnumerate_Deduplicates(t *testing.T) {
+	t.Parallel()
+
+	// ambiguous grammar, "aa" has two derivations
+	g, err := bnf.LoadGrammarString(`<S> ::= ("a" | "aa")+`)
+	assert.NoError(t, err)
+
+	got, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "aa", "aaa"}, got)
+}
+
+func TestEnumerate_Hour(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+
+	got, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 2})
+	assert.NoError(t, err)
+	assert.Len(t, got, 110) // 10 single digits and 100 two-digit hours
+	assert.Equal(t, "0", got[0])
+	assert.Equal(t, "99", got[len(got)-1])
+
+	got, err = g.Enumerate(bnf.EnumerateOptions{MaxLen: 3, MaxCount: 5})
+	assert.NoError(t, err)
+	assert.Len(t, got, 5)
+}
+
+func TestEnumerate_Depth(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= "(" <S> ")" | "x"`)
+	assert.NoError(t, err)
+
+	got, err := g.Enumerate(bnf.EnumerateOptions{MaxDepth: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "(x)", "((x))"}, got)
+}
+
+func TestEnumerate_Regex(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= /[ab]c?/`)
+	assert.NoError(t, err)
+
+	got, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "ac", "bc"}, got)
+}
+
+func TestEnumerate_Errors(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= "a"*`)
+	assert.NoError(t, err)
+
+	_, err = g.Enumerate(bnf.EnumerateOptions{})
+	assert.ErrorContains(t, err, "bound")
+
+	_, err = g.Enumerate(bnf.EnumerateOptions{MaxLen: 10, MaxSet: 5})
+	assert.ErrorContains(t, err, "exceeded")
+}
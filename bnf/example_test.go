@@ -1,11 +1,24 @@
 package bnf_test
 
 import (
+	"embed"
 	"fmt"
 	"github.com/tgagor/go-bnf/bnf"
 	"log"
 )
 
+//go:embed testdata/*.bnf
+var grammars embed.FS
+
+var sum = bnf.MustLoadGrammar(grammars, "testdata/sum.bnf")
+
+func ExampleMustLoadGrammar() {
+	ok, _ := sum.Match("12+3")
+	fmt.Println(ok)
+	// Output:
+	// true
+}
+
 func ExampleGrammar_Validate() {
 	grammar, err := bnf.LoadGrammarString(`<number> ::= "0" | "1"`)
 	if err != nil {
@@ -0,0 +1,471 @@
+package bnf
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Formats supported by ExportGrammar.
+const (
+	ExportISO   = "ebnf"  // ISO/IEC 14977 EBNF
+	ExportABNF  = "abnf"  // RFC 5234 ABNF with RFC 7405 case-sensitive strings
+	ExportW3C   = "w3c"   // EBNF notation of the W3C XML specification
+	ExportANTLR = "antlr" // ANTLR4 combined grammar (.g4)
+	ExportPEG   = "peg"   // PEG as accepted by pigeon
+)
+
+var exportFormatNames = map[string]string{
+	ExportISO:   "ISO EBNF",
+	ExportABNF:  "ABNF",
+	ExportW3C:   "W3C EBNF",
+	ExportANTLR: "ANTLR4",
+	ExportPEG:   "PEG",
+}
+
+// ExportFormats lists the formats supported by ExportGrammar.
+var ExportFormats = []string{ExportISO, ExportABNF, ExportW3C, ExportANTLR, ExportPEG}
+
+// ExportOptions configures ExportGrammar.
+type ExportOptions struct {
+	Format string // one of ExportFormats
+	Name   string // grammar name where the format needs one (ANTLR), default "Grammar"
+}
+
+// ExportGrammar writes the grammar in the syntax of another parser toolchain.
+// Regular expression terminals are translated into the format's own constructs
+// where possible. Constructs the format cannot express are emitted as the
+// format's closest placeholder (a comment, prose or special sequence) and
+// reported in the returned warnings, as are semantic differences like PEG's
+// ordered choice.
+func ExportGrammar(w io.Writer, ast *GrammarAST, opts ExportOptions) ([]string, error) {
+	if !slices.Contains(ExportFormats, opts.Format) {
+		return nil, fmt.Errorf("unknown export format %q, expected one of %s", opts.Format, strings.Join(ExportFormats, ", "))
+	}
+	if len(ast.Rules) == 0 {
+		return nil, fmt.Errorf("empty grammar")
+	}
+	ast, err := expandParams(ast)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Name == "" {
+		opts.Name = "Grammar"
+	}
+
+	ex := &exporter{format: opts.Format, names: map[string]string{}, tokens: map[string]string{}}
+	used := map[string]bool{}
+	for _, r := range ast.Rules {
+		if _, ok := ex.names[r.Name]; ok {
+			continue
+		}
+		name := ex.ruleName(r.Name)
+		base := name
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s%s%d", base, ex.sep(), i)
+		}
+		used[name] = true
+		ex.names[r.Name] = name
+	}
+
+	var sb strings.Builder
+	if ex.format == ExportANTLR {
+		fmt.Fprintf(&sb, "grammar %s;\n\n", antlrIdent(opts.Name, true))
+	}
+	if ast.Skip != nil {
+		ex.warn(fmt.Sprintf("@skip cannot be expressed in %s, the exported grammar allows no text between elements", exportFormatNames[ex.format]))
+	}
+	if slices.ContainsFunc(ast.Rules, func(r *RuleAST) bool { return slices.Contains(r.Annotations, "token") }) {
+		ex.warn("@token rules are exported as plain rules, the exported grammar neither scans tokens first nor reserves keywords")
+	}
+	if ex.format == ExportPEG {
+		ex.warn("PEG alternatives are ordered and repetitions greedy, alternatives sharing a prefix may need reordering")
+		for _, name := range leftRecursiveRules(ast) {
+			ex.warn(fmt.Sprintf("rule <%s> is left-recursive, which PEG does not support", name))
+		}
+	}
+
+	width := 0
+	for _, r := range ast.Rules {
+		width = max(width, len(ex.names[r.Name]))
+	}
+	seen := map[string]bool{}
+	for _, r := range ast.Rules {
+		ex.rule = r.Name
+		body := ex.print(ex.fromAST(r.Expr), 0)
+		name := ex.names[r.Name]
+		if seen[r.Name] {
+			// BNF allows a rule to be defined again, replacing the earlier definition
+			ex.warn(fmt.Sprintf("rule <%s> is defined more than once, only the last definition is used by go-bnf", r.Name))
+		}
+		seen[r.Name] = true
+		sb.WriteString(ex.define(name, width, body))
+	}
+
+	if len(ex.tokenOrder) > 0 {
+		sb.WriteString("\n")
+		for _, pattern := range ex.tokenOrder {
+			sb.WriteString(ex.tokens[pattern])
+		}
+	}
+
+	_, err = io.WriteString(w, sb.String())
+	return ex.warnings, err
+}
+
+// Expressions of the exporters: GrammarAST nodes with regular expressions translated.
+type (
+	exAlt struct{ opts []exNode }
+	exSeq struct{ elems []exNode }
+	exRep struct {
+		node exNode
+		min  int
+		max  int // -1 = infinity
+	}
+	exLit struct {
+		s    string
+		fold bool // case-insensitive, only in formats supporting it
+	}
+	exRef   struct{ name string }
+	exClass struct{ ranges []rune }       // pairs of inclusive bounds
+	exRaw   struct{ pattern string }      // regex which cannot be translated
+	exToken struct{ name string }         // ANTLR lexer rule standing for a regex
+	exNot   struct{ node exNode }         // negative lookahead
+	exEnd   struct{}                      // end of input
+	exDiff  struct{ node, except exNode } // difference
+)
+
+type exNode any
+
+type exporter struct {
+	format   string
+	names    map[string]string // BNF rule name -> exported name
+	rule     string            // rule being exported, for warnings
+	warnings []string
+
+	tokens     map[string]string // ANTLR lexer rules by regex pattern
+	tokenOrder []string
+}
+
+func (ex *exporter) warn(msg string) {
+	if !slices.Contains(ex.warnings, msg) {
+		ex.warnings = append(ex.warnings, msg)
+	}
+}
+
+func (ex *exporter) fromAST(e ExprAST) exNode {
+	switch t := e.(type) {
+	case *ChoiceAST:
+		out := &exAlt{}
+		for _, o := range t.Options {
+			out.opts = append(out.opts, ex.fromAST(o))
+		}
+		return out
+	case *SeqAST:
+		out := &exSeq{}
+		for _, el := range t.Elements {
+			out.elems = append(out.elems, ex.fromAST(el))
+		}
+		return out
+	case *RepeatAST:
+		return &exRep{node: ex.fromAST(t.Node), min: t.Min, max: t.Max}
+	case *IdentAST:
+		return &exRef{name: t.Name}
+	case *StringAST:
+		if t.IgnoreCase {
+			return ex.fromCaseless(t.Value)
+		}
+		return &exLit{s: t.Value}
+	case *RegexAST:
+		return ex.fromRegex(t.Pattern)
+	case *CharClassAST:
+		return ex.fromRegex(t.pattern())
+	case *BuiltinAST:
+		if b := lookupBuiltin(t.Name); b != nil {
+			return ex.fromRegex(b.re.Re.String())
+		}
+	case *AnyAST:
+		return ex.fromRegex(anyRuneRegex.Re.String())
+	case *EndAST:
+		return &exEnd{}
+	case *NotAST:
+		return &exNot{node: ex.fromAST(t.Node)}
+	case *DropAST:
+		// parse trees are not part of the exported grammar
+		return ex.fromAST(t.Node)
+	case *DiffAST:
+		return &exDiff{node: ex.fromAST(t.Node), except: ex.fromAST(t.Except)}
+	case *SepListAST:
+		return ex.fromAST(t.desugar())
+	}
+	return &exRaw{pattern: fmt.Sprintf("%T", e)}
+}
+
+// fromCaseless translates a case-insensitive literal. ABNF strings of printable
+// ASCII and PEG strings can ignore case, elsewhere every letter becomes a class
+// of its case variants, which ANTLR allows in lexer rules only.
+func (ex *exporter) fromCaseless(s string) exNode {
+	switch ex.format {
+	case ExportANTLR:
+		return ex.fromRegex("(?i:" + regexp.QuoteMeta(s) + ")")
+	case ExportABNF:
+		if strings.IndexFunc(s, func(r rune) bool { return r < 0x20 || r > 0x7e || r == '"' }) < 0 {
+			return &exLit{s: s, fold: true}
+		}
+	case ExportPEG:
+		return &exLit{s: s, fold: true}
+	}
+
+	seq := &exSeq{}
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			seq.elems = append(seq.elems, &exLit{s: run.String()})
+			run.Reset()
+		}
+	}
+	for _, r := range s {
+		ranges := []rune{r, r}
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			ranges = append(ranges, f, f)
+		}
+		if len(ranges) == 2 {
+			run.WriteRune(r)
+			continue
+		}
+		flush()
+		seq.elems = append(seq.elems, &exClass{ranges: normalizeRanges(ranges)})
+	}
+	flush()
+	if len(seq.elems) == 1 {
+		return seq.elems[0]
+	}
+	return seq
+}
+
+// fromRegex translates a regex terminal, warning when it cannot be expressed.
+func (ex *exporter) fromRegex(pattern string) exNode {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	var n exNode
+	if err == nil {
+		n = regexNode(re.Simplify())
+	}
+	if n == nil {
+		ex.warn(fmt.Sprintf("rule <%s>: /%s/ cannot be expressed in %s", ex.rule, pattern, exportFormatNames[ex.format]))
+		n = &exRaw{pattern: pattern}
+	}
+
+	if ex.format != ExportANTLR {
+		return n
+	}
+	// ANTLR allows character sets in lexer rules only, so regexes become tokens
+	ex.warn("regex terminals became lexer rules, the ANTLR lexer splits the input into tokens before parsing")
+	if _, ok := ex.tokens[pattern]; !ok {
+		name := fmt.Sprintf("REGEX%d", len(ex.tokenOrder)+1)
+		def := fmt.Sprintf("%s : %s ;\n", name, ex.print(n, 0))
+		if raw, ok := n.(*exRaw); ok {
+			def = fmt.Sprintf("%s : %s ; // not translated\n", name, antlrLiteral("/"+raw.pattern+"/"))
+		}
+		ex.tokens[pattern] = def
+		ex.tokenOrder = append(ex.tokenOrder, pattern)
+	}
+	return &exToken{name: strings.Fields(ex.tokens[pattern])[0]}
+}
+
+// regexNode translates a simplified regex, returning nil for constructs
+// without a grammar counterpart like anchors.
+func regexNode(re *syntax.Regexp) exNode {
+	sub := func() []exNode {
+		var out []exNode
+		for _, s := range re.Sub {
+			n := regexNode(s)
+			if n == nil {
+				return nil
+			}
+			out = append(out, n)
+		}
+		return out
+	}
+
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return &exSeq{}
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase == 0 {
+			return &exLit{s: string(re.Rune)}
+		}
+		seq := &exSeq{}
+		for _, r := range re.Rune {
+			var ranges []rune
+			for f := r; ; {
+				ranges = append(ranges, f, f)
+				if f = unicode.SimpleFold(f); f == r {
+					break
+				}
+			}
+			seq.elems = append(seq.elems, &exClass{ranges: normalizeRanges(ranges)})
+		}
+		return seq
+	case syntax.OpCharClass:
+		return &exClass{ranges: normalizeRanges(slices.Clone(re.Rune))}
+	case syntax.OpAnyCharNotNL:
+		return &exClass{ranges: []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}}
+	case syntax.OpAnyChar:
+		return &exClass{ranges: []rune{0, unicode.MaxRune}}
+	case syntax.OpCapture:
+		return regexNode(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		n := regexNode(re.Sub[0])
+		if n == nil {
+			return nil
+		}
+		rep := &exRep{node: n, max: -1}
+		switch re.Op {
+		case syntax.OpPlus:
+			rep.min = 1
+		case syntax.OpQuest:
+			rep.max = 1
+		}
+		return rep
+	case syntax.OpConcat:
+		if elems := sub(); elems != nil {
+			return &exSeq{elems: elems}
+		}
+	case syntax.OpAlternate:
+		if opts := sub(); opts != nil {
+			return &exAlt{opts: opts}
+		}
+	}
+	return nil
+}
+
+// normalizeRanges sorts and merges pairs of inclusive bounds.
+func normalizeRanges(r []rune) []rune {
+	type span struct{ lo, hi rune }
+	spans := make([]span, 0, len(r)/2)
+	for i := 0; i+1 < len(r); i += 2 {
+		spans = append(spans, span{r[i], r[i+1]})
+	}
+	slices.SortFunc(spans, func(a, b span) int { return int(a.lo - b.lo) })
+	var out []rune
+	for _, s := range spans {
+		if n := len(out); n > 0 && s.lo <= out[n-1]+1 {
+			out[n-1] = max(out[n-1], s.hi)
+			continue
+		}
+		out = append(out, s.lo, s.hi)
+	}
+	return out
+}
+
+// leftRecursiveRules lists the rules which can derive themselves without consuming input first.
+func leftRecursiveRules(ast *GrammarAST) []string {
+	exprs := map[string]ExprAST{}
+	for _, r := range ast.Rules {
+		exprs[r.Name] = r.Expr
+	}
+
+	nullable := map[string]bool{}
+	var isNullable func(e ExprAST) bool
+	isNullable = func(e ExprAST) bool {
+		switch t := e.(type) {
+		case *ChoiceAST:
+			return slices.ContainsFunc(t.Options, isNullable)
+		case *SeqAST:
+			for _, el := range t.Elements {
+				if !isNullable(el) {
+					return false
+				}
+			}
+			return true
+		case *RepeatAST:
+			return t.Min == 0 || isNullable(t.Node)
+		case *IdentAST:
+			return nullable[t.Name]
+		case *StringAST:
+			return t.Value == ""
+		case *EndAST, *NotAST:
+			return true
+		case *DropAST:
+			return isNullable(t.Node)
+		case *DiffAST:
+			return isNullable(t.Node)
+		case *SepListAST:
+			return t.Min == 0 || isNullable(t.Node)
+		case *RegexAST:
+			re, err := regexp.Compile(t.Pattern)
+			return err == nil && re.MatchString("")
+		}
+		return false
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, e := range exprs {
+			if !nullable[name] && isNullable(e) {
+				nullable[name] = true
+				changed = true
+			}
+		}
+	}
+
+	// rules referenced before any input is consumed
+	var first func(e ExprAST, out map[string]bool)
+	first = func(e ExprAST, out map[string]bool) {
+		switch t := e.(type) {
+		case *ChoiceAST:
+			for _, o := range t.Options {
+				first(o, out)
+			}
+		case *SeqAST:
+			for _, el := range t.Elements {
+				first(el, out)
+				if !isNullable(el) {
+					return
+				}
+			}
+		case *RepeatAST:
+			first(t.Node, out)
+		case *NotAST:
+			first(t.Node, out)
+		case *DropAST:
+			first(t.Node, out)
+		case *DiffAST:
+			first(t.Node, out)
+		case *SepListAST:
+			first(t.desugar(), out)
+		case *IdentAST:
+			out[t.Name] = true
+		}
+	}
+
+	var out []string
+	for _, r := range ast.Rules {
+		if slices.Contains(out, r.Name) {
+			continue
+		}
+		seen := map[string]bool{}
+		queue := []string{r.Name}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			next := map[string]bool{}
+			first(exprs[name], next)
+			for n := range next {
+				if n == r.Name {
+					out = append(out, r.Name)
+					queue = nil
+					break
+				}
+				if !seen[n] {
+					seen[n] = true
+					queue = append(queue, n)
+				}
+			}
+		}
+	}
+	return out
+}
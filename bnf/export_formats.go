@@ -0,0 +1,489 @@
+package bnf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Precedences of exported expressions, operands binding tighter get higher ones.
+const (
+	precAlt = iota
+	precSeq
+	precPrefix // operand of a prefix operator like PEG's !
+	precAtom
+)
+
+// maxEnumeratedClass is the largest character class ISO EBNF gets as a choice of characters.
+const maxEnumeratedClass = 32
+
+var antlrKeywords = map[string]bool{
+	"catch": true, "finally": true, "fragment": true, "grammar": true, "import": true,
+	"lexer": true, "locals": true, "mode": true, "options": true, "parser": true,
+	"returns": true, "throws": true, "tokens": true,
+}
+
+// ruleName converts a BNF rule name into an identifier of the format.
+func (ex *exporter) ruleName(name string) string {
+	if strings.ContainsRune(name, '<') {
+		// an instance of a parameterized rule like sep-list<id,",">, keep
+		// the words of its name and arguments only
+		words := strings.FieldsFunc(name, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-.", r)
+		})
+		name = strings.Join(words, "_")
+	}
+	switch ex.format {
+	case ExportISO:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, name)
+	case ExportABNF:
+		name = strings.NewReplacer("_", "-", ".", "-").Replace(name)
+		if r, _ := utf8.DecodeRuneInString(name); !isASCIILetter(r) {
+			name = "r-" + name
+		}
+		return name
+	case ExportANTLR:
+		return antlrIdent(name, false)
+	case ExportPEG:
+		name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+		if r, _ := utf8.DecodeRuneInString(name); !isIdentStart(r) {
+			name = "R_" + name
+		}
+		return name
+	}
+	return name
+}
+
+// sep is the word separator used to make exported names unique.
+func (ex *exporter) sep() string {
+	if ex.format == ExportABNF {
+		return "-"
+	}
+	return "_"
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// antlrIdent makes a parser rule name (lowercase start) or a grammar name (uppercase start).
+func antlrIdent(name string, upper bool) string {
+	name = strings.Map(func(r rune) rune {
+		if isASCIILetter(r) || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	r, size := utf8.DecodeRuneInString(name)
+	switch {
+	case !isASCIILetter(r) && upper:
+		name = "G" + name
+	case !isASCIILetter(r):
+		name = "r" + name
+	case upper:
+		name = string(unicode.ToUpper(r)) + name[size:]
+	default:
+		name = string(unicode.ToLower(r)) + name[size:]
+	}
+	if antlrKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// define returns the definition of a rule with its name padded to width.
+func (ex *exporter) define(name string, width int, body string) string {
+	pad := strings.Repeat(" ", width-len(name))
+	body = strings.TrimRight(body, " ")
+	switch ex.format {
+	case ExportISO:
+		return fmt.Sprintf("%s%s = %s ;\n", name, pad, body)
+	case ExportABNF:
+		return fmt.Sprintf("%s%s = %s\n", name, pad, body)
+	case ExportW3C:
+		return fmt.Sprintf("%s%s ::= %s\n", name, pad, body)
+	case ExportANTLR:
+		return fmt.Sprintf("%s%s : %s ;\n", name, pad, body)
+	}
+	return fmt.Sprintf("%s%s <- %s\n", name, pad, body)
+}
+
+// print renders n, in parentheses when it binds looser than prec.
+func (ex *exporter) print(n exNode, prec int) string {
+	s, p := ex.render(n)
+	if p < prec {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+func (ex *exporter) render(n exNode) (string, int) {
+	switch t := n.(type) {
+	case *exAlt:
+		sep := " | "
+		if ex.format == ExportABNF || ex.format == ExportPEG {
+			sep = " / "
+		}
+		parts := make([]string, len(t.opts))
+		for i, o := range t.opts {
+			parts[i] = ex.print(o, precSeq)
+		}
+		return strings.Join(parts, sep), precAlt
+	case *exSeq:
+		if len(t.elems) == 0 {
+			return ex.empty(), precAtom
+		}
+		if len(t.elems) == 1 {
+			return ex.render(t.elems[0])
+		}
+		sep := " "
+		if ex.format == ExportISO {
+			sep = ", "
+		}
+		var parts []string
+		for _, el := range t.elems {
+			if inner, ok := el.(*exSeq); ok && len(inner.elems) > 1 {
+				s, _ := ex.render(inner)
+				parts = append(parts, s)
+				continue
+			}
+			parts = append(parts, ex.print(el, precPrefix))
+		}
+		return strings.Join(parts, sep), precSeq
+	case *exRep:
+		return ex.repeat(t)
+	case *exRef:
+		return ex.names[t.name], precAtom
+	case *exToken:
+		return t.name, precAtom
+	case *exLit:
+		if t.fold {
+			return ex.caselessLiteral(t.s), precAtom
+		}
+		return ex.literal(t.s)
+	case *exClass:
+		return ex.class(t.ranges)
+	case *exRaw:
+		return ex.raw(t.pattern), precAtom
+	case *exNot:
+		if ex.format == ExportPEG {
+			return "!" + ex.print(t.node, precAtom), precPrefix
+		}
+		ex.warn(fmt.Sprintf("rule <%s>: negative lookahead cannot be expressed in %s", ex.rule, exportFormatNames[ex.format]))
+		return ex.prose("not " + ex.print(t.node, precAtom)), precAtom
+	case *exDiff:
+		switch ex.format {
+		case ExportISO, ExportW3C:
+			return ex.print(t.node, precAtom) + " - " + ex.print(t.except, precAtom), precSeq
+		case ExportPEG:
+			ex.warn(fmt.Sprintf("rule <%s>: difference emitted as a negative lookahead, which also rejects text merely starting with the exception", ex.rule))
+			return "!" + ex.print(t.except, precAtom) + " " + ex.print(t.node, precPrefix), precSeq
+		}
+		ex.warn(fmt.Sprintf("rule <%s>: difference cannot be expressed in %s", ex.rule, exportFormatNames[ex.format]))
+		return ex.print(t.node, precPrefix) + " " + ex.prose("except "+ex.print(t.except, precAtom)), precSeq
+	case *exEnd:
+		switch ex.format {
+		case ExportPEG:
+			return "!.", precPrefix
+		case ExportANTLR:
+			return "EOF", precAtom
+		}
+		ex.warn(fmt.Sprintf("rule <%s>: end of input cannot be expressed in %s", ex.rule, exportFormatNames[ex.format]))
+		return ex.prose("end of input"), precAtom
+	}
+	return fmt.Sprintf("%T", n), precAtom
+}
+
+func (ex *exporter) empty() string {
+	switch ex.format {
+	case ExportISO, ExportANTLR:
+		return ""
+	}
+	return `""`
+}
+
+func (ex *exporter) repeat(t *exRep) (string, int) {
+	switch ex.format {
+	case ExportISO:
+		inner := ex.print(t.node, precAlt)
+		switch {
+		case t.max == 1:
+			return "[ " + inner + " ]", precAtom
+		case t.min > 0:
+			return ex.print(t.node, precAtom) + ", { " + inner + " }", precSeq
+		}
+		return "{ " + inner + " }", precAtom
+	case ExportABNF:
+		inner := ex.print(t.node, precAtom)
+		switch {
+		case t.max == 1:
+			return "[" + ex.print(t.node, precAlt) + "]", precAtom
+		case t.min > 0:
+			return fmt.Sprintf("%d*%s", t.min, inner), precAtom
+		}
+		return "*" + inner, precAtom
+	}
+
+	op := "*"
+	switch {
+	case t.max == 1:
+		op = "?"
+	case t.min > 0:
+		op = "+"
+	}
+	return ex.print(t.node, precAtom) + op, precAtom
+}
+
+func (ex *exporter) raw(pattern string) string {
+	if ex.format == ExportABNF {
+		return ex.prose("regex /" + pattern + "/")
+	}
+	return ex.prose("/" + pattern + "/")
+}
+
+// prose writes text the format cannot express as a special sequence, prose
+// value or comment.
+func (ex *exporter) prose(text string) string {
+	switch ex.format {
+	case ExportISO:
+		return "? " + strings.ReplaceAll(text, "?", `\?`) + " ?"
+	case ExportABNF:
+		return "<" + strings.ReplaceAll(text, ">", `\x3E`) + ">"
+	}
+	return "/* " + strings.ReplaceAll(text, "*/", `*\/`) + " */"
+}
+
+func (ex *exporter) literal(s string) (string, int) {
+	if s == "" {
+		return ex.empty(), precAtom
+	}
+	switch ex.format {
+	case ExportISO:
+		if strings.IndexFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 ||
+			strings.Contains(s, `"`) && strings.Contains(s, `'`) {
+			ex.warn(fmt.Sprintf("rule <%s>: literal %q cannot be expressed in ISO EBNF, emitted as a special sequence", ex.rule, s))
+			return "? " + strings.ReplaceAll(strconv.Quote(s), "?", `\?`) + " ?", precAtom
+		}
+		if strings.Contains(s, `"`) {
+			return "'" + s + "'", precAtom
+		}
+		return `"` + s + `"`, precAtom
+	case ExportABNF:
+		if strings.IndexFunc(s, func(r rune) bool { return r < 0x20 || r > 0x7e || r == '"' }) >= 0 {
+			codes := make([]string, 0, len(s))
+			for _, r := range s {
+				codes = append(codes, fmt.Sprintf("%X", r))
+			}
+			return "%x" + strings.Join(codes, "."), precAtom
+		}
+		if strings.IndexFunc(s, isASCIILetter) >= 0 {
+			return `%s"` + s + `"`, precAtom
+		}
+		return `"` + s + `"`, precAtom
+	case ExportW3C:
+		return w3cLiteral(s)
+	case ExportANTLR:
+		return antlrLiteral(s), precAtom
+	}
+	return strconv.Quote(s), precAtom
+}
+
+// caselessLiteral writes a case-insensitive literal in ABNF or PEG.
+func (ex *exporter) caselessLiteral(s string) string {
+	if ex.format == ExportPEG {
+		return strconv.Quote(s) + "i"
+	}
+	return `"` + s + `"`
+}
+
+// w3cLiteral quotes runs of printable characters and writes the others as #xN.
+func w3cLiteral(s string) (string, int) {
+	var parts []string
+	var run strings.Builder
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		q := `"`
+		if strings.Contains(run.String(), `"`) {
+			q = "'"
+		}
+		parts = append(parts, q+run.String()+q)
+		run.Reset()
+	}
+	for _, r := range s {
+		// a run may hold one kind of quote only
+		if !unicode.IsPrint(r) || r == '\'' && strings.Contains(run.String(), `"`) || r == '"' && strings.Contains(run.String(), "'") {
+			if unicode.IsPrint(r) {
+				flush()
+				run.WriteRune(r)
+				continue
+			}
+			flush()
+			parts = append(parts, fmt.Sprintf("#x%X", r))
+			continue
+		}
+		run.WriteRune(r)
+	}
+	flush()
+	if len(parts) == 1 {
+		return parts[0], precAtom
+	}
+	return strings.Join(parts, " "), precSeq
+}
+
+func antlrLiteral(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for _, r := range s {
+		sb.WriteString(antlrEscape(r, "'\\"))
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+// antlrEscape escapes r for an ANTLR literal or set, special being the characters needing a backslash.
+func antlrEscape(r rune, special string) string {
+	switch {
+	case strings.ContainsRune(special, r):
+		return `\` + string(r)
+	case r == '\n':
+		return `\n`
+	case r == '\r':
+		return `\r`
+	case r == '\t':
+		return `\t`
+	case unicode.IsPrint(r) && r < 0x10000:
+		return string(r)
+	case r > 0xFFFF:
+		return fmt.Sprintf(`\u{%X}`, r)
+	}
+	return fmt.Sprintf(`\u%04X`, r)
+}
+
+func (ex *exporter) class(ranges []rune) (string, int) {
+	switch ex.format {
+	case ExportISO:
+		size := 0
+		for i := 0; i < len(ranges); i += 2 {
+			size += int(ranges[i+1]-ranges[i]) + 1
+		}
+		if size > maxEnumeratedClass {
+			ex.warn(fmt.Sprintf("rule <%s>: character class %s is too large for ISO EBNF, emitted as a special sequence", ex.rule, pegClass(ranges)))
+			return "? " + pegClass(ranges) + " ?", precAtom
+		}
+		var alts []string
+		for i := 0; i < len(ranges); i += 2 {
+			for r := ranges[i]; r <= ranges[i+1]; r++ {
+				s, _ := ex.literal(string(r))
+				alts = append(alts, s)
+			}
+		}
+		if len(alts) == 1 {
+			return alts[0], precAtom
+		}
+		return strings.Join(alts, " | "), precAlt
+	case ExportABNF:
+		var alts []string
+		for i := 0; i < len(ranges); i += 2 {
+			if ranges[i] == ranges[i+1] {
+				alts = append(alts, fmt.Sprintf("%%x%X", ranges[i]))
+			} else {
+				alts = append(alts, fmt.Sprintf("%%x%X-%X", ranges[i], ranges[i+1]))
+			}
+		}
+		if len(alts) == 1 {
+			return alts[0], precAtom
+		}
+		return strings.Join(alts, " / "), precAlt
+	case ExportW3C:
+		var sb strings.Builder
+		sb.WriteByte('[')
+		if neg, ok := complement(ranges); ok {
+			sb.WriteByte('^')
+			ranges = neg
+		}
+		char := func(r rune) {
+			if unicode.IsPrint(r) && r < 0x80 && !strings.ContainsRune(`]-^#\ `, r) {
+				sb.WriteRune(r)
+			} else {
+				fmt.Fprintf(&sb, "#x%X", r)
+			}
+		}
+		for i := 0; i < len(ranges); i += 2 {
+			char(max(ranges[i], 1)) // #x0 is not a character in XML
+			if ranges[i+1] != ranges[i] {
+				sb.WriteByte('-')
+				char(ranges[i+1])
+			}
+		}
+		sb.WriteByte(']')
+		return sb.String(), precAtom
+	case ExportANTLR:
+		var sb strings.Builder
+		if neg, ok := complement(ranges); ok {
+			sb.WriteByte('~')
+			ranges = neg
+		}
+		sb.WriteByte('[')
+		for i := 0; i < len(ranges); i += 2 {
+			sb.WriteString(antlrEscape(ranges[i], `]\-`))
+			if ranges[i+1] != ranges[i] {
+				sb.WriteByte('-')
+				sb.WriteString(antlrEscape(ranges[i+1], `]\-`))
+			}
+		}
+		sb.WriteByte(']')
+		return sb.String(), precAtom
+	}
+	return pegClass(ranges), precAtom
+}
+
+// pegClass renders ranges as a bracketed class with Go escapes, as pigeon reads it.
+func pegClass(ranges []rune) string {
+	if len(ranges) == 2 && ranges[0] == 0 && ranges[1] == unicode.MaxRune {
+		return "."
+	}
+	char := func(r rune) string {
+		if strings.ContainsRune(`]\-^`, r) {
+			return `\` + string(r)
+		}
+		q := strconv.QuoteRune(r)
+		return q[1 : len(q)-1]
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	if neg, ok := complement(ranges); ok {
+		sb.WriteByte('^')
+		ranges = neg
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		sb.WriteString(char(ranges[i]))
+		if ranges[i+1] != ranges[i] {
+			sb.WriteByte('-')
+			sb.WriteString(char(ranges[i+1]))
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// complement returns the ranges missing from a class spanning all of Unicode
+// but a few characters, which read better as a negated class.
+func complement(ranges []rune) ([]rune, bool) {
+	if len(ranges) < 4 || ranges[0] != 0 || ranges[len(ranges)-1] != unicode.MaxRune {
+		return nil, false
+	}
+	var out []rune
+	for i := 1; i+1 < len(ranges); i += 2 {
+		out = append(out, ranges[i]+1, ranges[i+1]-1)
+	}
+	return out, true
+}
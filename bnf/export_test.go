@@ -0,0 +1,130 @@
+package bnf_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const exportGrammar = `
+<list>  ::= <list> "," <item> | <item>
+<item>  ::= <ident> | <num>? "x"
+<ident> ::= /[a-c][a-c0-9]*/
+<num>   ::= "1"+ | "it's \"q\"" | ""
+`
+
+func exportString(t *testing.T, src string, format string) (string, []string) {
+	t.Helper()
+	p, err := bnf.NewParser(strings.NewReader(src))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	var sb strings.Builder
+	warnings, err := bnf.ExportGrammar(&sb, ast, bnf.ExportOptions{Format: format, Name: "list"})
+	assert.NoError(t, err)
+	return sb.String(), warnings
+}
+
+func TestExportGrammar(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		format   string
+		want     string
+		warnings []string
+	}{
+		{
+			format: bnf.ExportISO,
+			want: `list  = list, ",", item | item ;
+item  = ident | [ num ], "x" ;
+ident = ("a" | "b" | "c"), { "0" | "1" | "2" | "3" | "4" | "5" | "6" | "7" | "8" | "9" | "a" | "b" | "c" } ;
+num   = "1", { "1" } | ? "it's \"q\"" ? | ;
+`,
+			warnings: []string{`rule <num>: literal "it's \"q\"" cannot be expressed in ISO EBNF, emitted as a special sequence`},
+		},
+		{
+			format: bnf.ExportABNF,
+			want: `list  = list "," item / item
+item  = ident / [num] %s"x"
+ident = %x61-63 *(%x30-39 / %x61-63)
+num   = 1*"1" / %x69.74.27.73.20.22.71.22 / ""
+`,
+		},
+		{
+			format: bnf.ExportW3C,
+			want: `list  ::= list "," item | item
+item  ::= ident | num? "x"
+ident ::= [a-c] [0-9a-c]*
+num   ::= "1"+ | "it's " '"q"' | ""
+`,
+		},
+		{
+			format: bnf.ExportANTLR,
+			want: `grammar List;
+
+list  : list ',' item | item ;
+item  : ident | num? 'x' ;
+ident : REGEX1 ;
+num   : '1'+ | 'it\'s "q"' | ;
+
+REGEX1 : [a-c] [0-9a-c]* ;
+`,
+			warnings: []string{"regex terminals became lexer rules, the ANTLR lexer splits the input into tokens before parsing"},
+		},
+		{
+			format: bnf.ExportPEG,
+			want: `list  <- list "," item / item
+item  <- ident / num? "x"
+ident <- [a-c] [0-9a-c]*
+num   <- "1"+ / "it's \"q\"" / ""
+`,
+			warnings: []string{
+				"PEG alternatives are ordered and repetitions greedy, alternatives sharing a prefix may need reordering",
+				"rule <list> is left-recursive, which PEG does not support",
+			},
+		},
+	} {
+		out, warnings := exportString(t, exportGrammar, tt.format)
+		assert.Equal(t, tt.want, out, tt.format)
+		assert.Equal(t, tt.warnings, warnings, tt.format)
+	}
+}
+
+func TestExportGrammar_Untranslatable(t *testing.T) {
+	t.Parallel()
+	src := "<line> ::= /^#.*/ <eol>\n<eol> ::= \"\\n\"\n"
+
+	out, warnings := exportString(t, src, bnf.ExportABNF)
+	assert.Equal(t, "line = <regex /^#.*/> eol\neol  = %xA\n", out)
+	assert.Equal(t, []string{"rule <line>: /^#.*/ cannot be expressed in ABNF"}, warnings)
+
+	out, _ = exportString(t, src, bnf.ExportANTLR)
+	assert.Contains(t, out, "REGEX1 : '/^#.*/' ; // not translated\n")
+
+	out, _ = exportString(t, "<a> ::= /(?i)ab/ /./\n", bnf.ExportPEG)
+	assert.Equal(t, "a <- [Aa] [Bb] [^\\n]\n", out)
+	out, _ = exportString(t, "<a> ::= /[^\"\\\\]/\n", bnf.ExportANTLR)
+	assert.Contains(t, out, `REGEX1 : ~["\\] ;`)
+	out, _ = exportString(t, "<a> ::= /[^-]/\n", bnf.ExportW3C)
+	assert.Equal(t, "a ::= [^#x2D]\n", out)
+}
+
+func TestExportGrammar_IndirectLeftRecursion(t *testing.T) {
+	t.Parallel()
+
+	_, warnings := exportString(t, "<a> ::= <b> \"x\" | \"y\"\n<b> ::= <c>? <a>\n<c> ::= \"c\"\n", bnf.ExportPEG)
+	assert.Contains(t, warnings, "rule <a> is left-recursive, which PEG does not support")
+	assert.Contains(t, warnings, "rule <b> is left-recursive, which PEG does not support")
+	assert.NotContains(t, warnings, "rule <c> is left-recursive, which PEG does not support")
+}
+
+func TestExportGrammar_UnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := bnf.ExportGrammar(&strings.Builder{}, &bnf.GrammarAST{}, bnf.ExportOptions{Format: "yacc"})
+	assert.ErrorContains(t, err, `unknown export format "yacc"`)
+}
@@ -0,0 +1,562 @@
+package bnf
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp/syntax"
+	"slices"
+	"strings"
+)
+
+// GenerateOptions controls the shape of the sentences produced by Grammar.Generate.
+type GenerateOptions struct {
+	Start     string               // rule to generate from, defaults to the grammar start rule
+	MaxDepth  int                  // rule nesting after which the shortest derivations are preferred (default 12)
+	MaxRepeat int                  // maximum iterations of unbounded repetitions (default 3)
+	MaxLength int                  // output length after which the shortest derivations are preferred (0 = no limit)
+	Weights   map[string][]float64 // relative weights of the alternatives of a rule's top-level choice
+}
+
+const (
+	infCost          = math.MaxInt / 2
+	maxGenerateDepth = 1000 // hard stop for derivations that never reach a terminal
+	maxAvoidTries    = 100  // attempts to generate text a lookahead or difference accepts
+)
+
+type generator struct {
+	g       *Grammar
+	r       *rand.Rand
+	opts    GenerateOptions
+	cost    map[string]int // minimal derivation depth of every rule
+	weights map[*choice][]float64
+	regex   map[*Regex]*syntax.Regexp
+	out     strings.Builder
+	depth   int
+	last    scanned // token generated last, with a nil def before the first
+
+	force   map[node]int      // one-shot decisions: alternative for choices, iterations for repetitions
+	hits    map[coverKey]bool // coverage points exercised by the last sentence, when tracked
+	covered map[coverKey]bool // points exercised before, alternatives not in it are preferred
+}
+
+// Generate produces a random sentence of the language described by the grammar.
+// It walks the rule graph picking alternatives and repetition counts at random;
+// once MaxDepth or MaxLength is exceeded it steers towards the shortest
+// derivations so that generation always terminates.
+func (g *Grammar) Generate(r *rand.Rand, opts GenerateOptions) (string, error) {
+	gen, err := g.newGenerator(r, opts)
+	if err != nil {
+		return "", err
+	}
+	return gen.sentence()
+}
+
+func (g *Grammar) newGenerator(r *rand.Rand, opts GenerateOptions) (*generator, error) {
+	if opts.Start == "" {
+		opts.Start = g.Start
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 12
+	}
+	if opts.MaxRepeat <= 0 {
+		opts.MaxRepeat = 3
+	}
+	if _, ok := g.Rules[opts.Start]; !ok {
+		return nil, fmt.Errorf("unknown start rule: %s", opts.Start)
+	}
+
+	gen := &generator{
+		g:       g,
+		r:       r,
+		opts:    opts,
+		cost:    ruleCosts(g.Rules),
+		weights: map[*choice][]float64{},
+		regex:   map[*Regex]*syntax.Regexp{},
+	}
+	for name, w := range opts.Weights {
+		rule, ok := g.Rules[name]
+		if !ok {
+			return nil, fmt.Errorf("weights given for unknown rule: %s", name)
+		}
+		c, ok := rule.Expr.(*choice)
+		if !ok || len(c.Options) != len(w) {
+			return nil, fmt.Errorf("weights for rule %s do not match its alternatives", name)
+		}
+		gen.weights[c] = w
+	}
+	if gen.cost[opts.Start] >= infCost {
+		return nil, fmt.Errorf("rule %s has no finite derivation", opts.Start)
+	}
+	return gen, nil
+}
+
+func (gen *generator) sentence() (string, error) {
+	gen.out.Reset()
+	gen.depth = 0
+	gen.last = scanned{}
+	if gen.hits != nil {
+		clear(gen.hits)
+	}
+	if err := gen.rule(gen.opts.Start, gen.g.Rules[gen.opts.Start]); err != nil {
+		return "", err
+	}
+	return gen.out.String(), nil
+}
+
+// ruleCosts computes the minimal derivation depth of every rule with a fixed-point
+// iteration. Rules that can never terminate keep infCost.
+func ruleCosts(rules map[string]*Rule) map[string]int {
+	cost := make(map[string]int, len(rules))
+	for name := range rules {
+		cost[name] = infCost
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, r := range rules {
+			if r.Expr == nil {
+				continue
+			}
+			if c := nodeCost(r.Expr, cost); c < cost[name] {
+				cost[name] = c
+				changed = true
+			}
+		}
+	}
+	return cost
+}
+
+func nodeCost(n node, cost map[string]int) int {
+	switch t := n.(type) {
+	case *nonTerminal:
+		c, ok := cost[t.Name]
+		if !ok || c >= infCost {
+			return infCost
+		}
+		return c + 1
+	case *sequence:
+		out := 0
+		for _, e := range t.Elements {
+			out = max(out, nodeCost(e, cost))
+		}
+		return out
+	case *choice:
+		out := infCost
+		for _, o := range t.Options {
+			out = min(out, nodeCost(o, cost))
+		}
+		return out
+	case *repeat:
+		if t.Min == 0 {
+			return 0
+		}
+		return nodeCost(t.Node, cost)
+	case *difference:
+		return nodeCost(t.Node, cost)
+	case *dropped:
+		return nodeCost(t.Node, cost)
+	case *token:
+		return nodeCost(t.def.node, cost)
+	}
+	return 0
+}
+
+// exhausted reports whether generation should now head for the shortest completion.
+func (gen *generator) exhausted() bool {
+	return gen.depth >= gen.opts.MaxDepth ||
+		(gen.opts.MaxLength > 0 && gen.out.Len() >= gen.opts.MaxLength)
+}
+
+func (gen *generator) rule(name string, rule *Rule) error {
+	if rule == nil {
+		return fmt.Errorf("NonTerminal without Rule: %s", name)
+	}
+	if gen.depth >= maxGenerateDepth {
+		return fmt.Errorf("rule %s has no finite derivation", name)
+	}
+	gen.hit(coverKey{rule: name})
+	gen.depth++
+	defer func() { gen.depth-- }()
+	return gen.node(rule.Expr)
+}
+
+func (gen *generator) node(n node) error {
+	switch t := n.(type) {
+	case *terminal:
+		gen.out.WriteString(t.Value)
+	case *caselessTerminal:
+		gen.out.WriteString(t.Value)
+	case *Regex:
+		return gen.regexp(t)
+	case regexer:
+		return gen.regexp(t.regex())
+	case *nonTerminal:
+		return gen.rule(t.Name, t.Rule)
+	case *sequence:
+		return gen.sequence(t.Elements, t.Skip)
+	case *skipper:
+		// once is enough to keep the elements around apart
+		return gen.node(t.Node)
+	case *endOfInput:
+		// generation ends with the start rule, the anchor produces no text
+	case *notPredicate:
+		// nothing follows within the sequence to check the predicate against
+	case *difference:
+		return gen.avoiding(t.Except, true, func() error { return gen.node(t.Node) })
+	case *dropped:
+		return gen.node(t.Node)
+	case *token:
+		return gen.token(t)
+	case *choice:
+		i, ok := gen.forced(t)
+		if !ok {
+			i = gen.pick(t)
+		}
+		gen.hit(coverKey{node: t, branch: i})
+		return gen.node(t.Options[i])
+	case *repeat:
+		n, ok := gen.forced(t)
+		if !ok {
+			n = gen.iterations(t.Min, -1)
+		}
+		gen.hit(coverKey{node: t, branch: min(n, 2)})
+		for i := range n {
+			if i > 0 && t.Skip != nil {
+				if err := gen.skipped(t.Skip, func() error { return gen.node(t.Node) }); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := gen.node(t.Node); err != nil {
+				return err
+			}
+		}
+	case *optional:
+		n, ok := gen.forced(t)
+		if !ok {
+			n = gen.iterations(0, 1)
+		}
+		gen.hit(coverKey{node: t, branch: n})
+		if n > 0 {
+			return gen.node(t.Node)
+		}
+	default:
+		return fmt.Errorf("cannot generate from node %T", n)
+	}
+	return nil
+}
+
+// sequence generates the elements of a sequence, with the text of skip
+// between them in syntactic rules.
+func (gen *generator) sequence(elems []node, skip node) error {
+	for i, e := range elems {
+		if i > 0 && skip != nil {
+			return gen.skipped(skip, func() error { return gen.sequence(elems[i:], skip) })
+		}
+		if p, ok := e.(*notPredicate); ok {
+			return gen.lookahead(p, elems[i+1:], skip)
+		}
+		if err := gen.node(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipped generates what produce generates preceded by the text of skip,
+// trying again while skip would consume more than its own text, like a line
+// comment running into the next element, or while the token before it would
+// run into the next one, like two identifiers with nothing between them.
+// Nothing is skipped before empty text, the next element keeps its own
+// distance.
+func (gen *generator) skipped(skip node, produce func() error) error {
+	mark, last := gen.out.Len(), gen.last
+	if err := produce(); err != nil {
+		return err
+	}
+	text := gen.out.String()
+	rest := text[mark:]
+	if rest == "" {
+		return nil
+	}
+	next := gen.last
+	for range maxAvoidTries {
+		gen.out.Reset()
+		gen.out.WriteString(text[:mark])
+		if err := gen.node(skip); err != nil {
+			return err
+		}
+		end := gen.out.Len() - mark
+		gen.out.WriteString(rest)
+		matches, err := NewContext(gen.out.String()[mark:]).Match(skip, 0)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 || matches[0].End != end {
+			continue
+		}
+		ok, err := gen.apart(last, gen.out.String())
+		if err != nil {
+			return err
+		}
+		if ok {
+			if next.pos >= mark {
+				next.pos += end
+				next.end += end
+			}
+			gen.last = next
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot generate text after %s", formatNode(skip))
+}
+
+// lookahead generates the elements following a negative lookahead, trying
+// again while the predicate matches their text. Text generated after the
+// enclosing sequence is not checked.
+func (gen *generator) lookahead(p *notPredicate, rest []node, skip node) error {
+	return gen.avoiding(p.Node, false, func() error {
+		return gen.sequence(rest, skip)
+	})
+}
+
+// token generates the text of a token, trying again while the scanner would
+// read another token from it, like a keyword where an identifier is due.
+// Without @skip, nothing can come between tokens, so the token before must
+// not run into it either; otherwise skipped keeps them apart.
+func (gen *generator) token(t *token) error {
+	mark, last := gen.out.Len(), gen.last
+	for range maxAvoidTries {
+		if err := gen.node(t.def.node); err != nil {
+			return err
+		}
+		text := gen.out.String()
+		tok, err := t.scanner.next(NewContext(text[mark:]), 0)
+		if err != nil {
+			return err
+		}
+		ok := tok.def == t.def && tok.end == len(text)-mark
+		if ok && t.scanner.skip == nil {
+			if ok, err = gen.apart(last, text); err != nil {
+				return err
+			}
+		}
+		if ok {
+			gen.last = scanned{def: t.def, pos: mark, end: len(text)}
+			return nil
+		}
+		gen.out.Reset()
+		gen.out.WriteString(text[:mark])
+	}
+	return fmt.Errorf("cannot generate text scanned as %s", t.def.kind)
+}
+
+// apart reports whether the scanner still reads tok in text, which goes on
+// past it, rather than a longer token running into what follows.
+func (gen *generator) apart(tok scanned, text string) (bool, error) {
+	if tok.def == nil {
+		return true, nil
+	}
+	next, err := gen.g.scanner.next(NewContext(text), tok.pos)
+	return next.def == tok.def && next.end == tok.end, err
+}
+
+// avoiding runs produce until n does not match the text it generated, at
+// its start or, with whole set, as a whole.
+func (gen *generator) avoiding(n node, whole bool, produce func() error) error {
+	mark, last := gen.out.Len(), gen.last
+	for range maxAvoidTries {
+		if err := produce(); err != nil {
+			return err
+		}
+		text := gen.out.String()
+		matches, err := NewContext(text[mark:]).Match(n, 0)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(matches, func(m MatchResult) bool { return !whole || m.End == len(text)-mark }) {
+			return nil
+		}
+		gen.out.Reset()
+		gen.out.WriteString(text[:mark])
+		gen.last = last
+	}
+	return fmt.Errorf("cannot generate text not matching %s", formatNode(n))
+}
+
+// forced returns and consumes the decision planned for n, if any.
+func (gen *generator) forced(n node) (int, bool) {
+	v, ok := gen.force[n]
+	if ok {
+		delete(gen.force, n)
+	}
+	return v, ok
+}
+
+func (gen *generator) hit(k coverKey) {
+	if gen.hits != nil {
+		gen.hits[k] = true
+	}
+}
+
+// pick selects the alternative of a choice to expand next.
+func (gen *generator) pick(c *choice) int {
+	costs := make([]int, len(c.Options))
+	best := infCost
+	for i, o := range c.Options {
+		costs[i] = nodeCost(o, gen.cost)
+		best = min(best, costs[i])
+	}
+
+	weights := make([]float64, len(c.Options))
+	total := 0.0
+	for i := range c.Options {
+		switch {
+		case costs[i] >= infCost:
+			continue // never terminates
+		case gen.exhausted() && costs[i] > best:
+			continue
+		case gen.weights[c] != nil:
+			weights[i] = gen.weights[c][i]
+		default:
+			weights[i] = 1
+		}
+		total += weights[i]
+	}
+	if gen.covered != nil {
+		total = gen.preferUncovered(c, weights, total)
+	}
+	if total <= 0 {
+		// all weighted alternatives disabled, fall back to the shortest one
+		for i := range costs {
+			if costs[i] == best {
+				return i
+			}
+		}
+	}
+
+	x := gen.r.Float64() * total
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return len(weights) - 1
+}
+
+// preferUncovered drops the weight of alternatives exercised before, unless
+// every eligible alternative was, and returns the new total weight.
+func (gen *generator) preferUncovered(c *choice, weights []float64, total float64) float64 {
+	fresh := 0.0
+	for i, w := range weights {
+		k := coverKey{node: c, branch: i}
+		if !gen.covered[k] && !gen.hits[k] {
+			fresh += w
+		}
+	}
+	if fresh <= 0 {
+		return total
+	}
+	for i := range weights {
+		k := coverKey{node: c, branch: i}
+		if gen.covered[k] || gen.hits[k] {
+			weights[i] = 0
+		}
+	}
+	return fresh
+}
+
+// iterations returns a random repetition count in [lo, hi] (hi < 0 = unbounded).
+func (gen *generator) iterations(lo, hi int) int {
+	if gen.exhausted() {
+		return lo
+	}
+	if hi < 0 {
+		hi = max(lo, gen.opts.MaxRepeat)
+	}
+	return lo + gen.r.IntN(hi-lo+1)
+}
+
+func (gen *generator) regexp(r *Regex) error {
+	re, ok := gen.regex[r]
+	if !ok {
+		var err error
+		re, err = syntax.Parse(r.Re.String(), syntax.Perl)
+		if err != nil {
+			return fmt.Errorf("invalid regex pattern %q: %w", r.Re.String(), err)
+		}
+		re = re.Simplify()
+		gen.regex[r] = re
+	}
+	gen.syntax(re)
+	return nil
+}
+
+func (gen *generator) syntax(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		gen.out.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		gen.out.WriteRune(gen.classRune(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		gen.out.WriteRune(rune(' ' + gen.r.IntN('~'-' '+1)))
+	case syntax.OpCapture:
+		gen.syntax(re.Sub[0])
+	case syntax.OpConcat:
+		for _, s := range re.Sub {
+			gen.syntax(s)
+		}
+	case syntax.OpAlternate:
+		if gen.exhausted() {
+			gen.syntax(re.Sub[0])
+		} else {
+			gen.syntax(re.Sub[gen.r.IntN(len(re.Sub))])
+		}
+	case syntax.OpStar:
+		for range gen.iterations(0, -1) {
+			gen.syntax(re.Sub[0])
+		}
+	case syntax.OpPlus:
+		for range gen.iterations(1, -1) {
+			gen.syntax(re.Sub[0])
+		}
+	case syntax.OpQuest:
+		for range gen.iterations(0, 1) {
+			gen.syntax(re.Sub[0])
+		}
+	case syntax.OpRepeat:
+		for range gen.iterations(re.Min, re.Max) {
+			gen.syntax(re.Sub[0])
+		}
+	}
+	// anchors, word boundaries and empty matches produce no text
+}
+
+// classRune picks a rune from a regexp character class given as [lo, hi] pairs.
+// Printable ASCII members are preferred to keep the output readable.
+func (gen *generator) classRune(ranges []rune) rune {
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		for c := max(ranges[i], ' '); c <= min(ranges[i+1], '~'); c++ {
+			ascii = append(ascii, c)
+		}
+	}
+	if len(ascii) > 0 {
+		return ascii[gen.r.IntN(len(ascii))]
+	}
+
+	total := 0
+	for i := 0; i < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	x := gen.r.IntN(total)
+	for i := 0; i < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if x < size {
+			return ranges[i] + rune(x)
+		}
+		x -= size
+	}
+	return ranges[0]
+}
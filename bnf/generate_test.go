@@ -0,0 +1,114 @@
+package bnf_test
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate_Examples(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"numbers", "hour", "postal", "left-recursive"} {
+		t.Run(file, func(t *testing.T) {
+			g, err := bnf.LoadGrammarFile("../examples/" + file + ".bnf")
+			assert.NoError(t, err)
+
+			r := rand.New(rand.NewPCG(42, 42))
+			for range 50 {
+				s, err := g.Generate(r, bnf.GenerateOptions{})
+				assert.NoError(t, err)
+
+				ok, err := g.Match(s)
+				assert.True(t, ok, s)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/numbers.bnf")
+	assert.NoError(t, err)
+
+	gen := func() []string {
+		r := rand.New(rand.NewPCG(7, 7))
+		var out []string
+		for range 10 {
+			s, err := g.Generate(r, bnf.GenerateOptions{})
+			assert.NoError(t, err)
+			out = append(out, s)
+		}
+		return out
+	}
+
+	assert.Equal(t, gen(), gen())
+}
+
+func TestGenerate_DepthBound(t *testing.T) {
+	t.Parallel()
+
+	// S only terminates through "b", depth bound must steer towards it
+	g, err := bnf.LoadGrammarString(`<S> ::= "a" <S> | "b"`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		s, err := g.Generate(r, bnf.GenerateOptions{MaxDepth: 3})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(s), 3)
+		assert.Regexp(t, `^a*b$`, s)
+	}
+}
+
+func TestGenerate_Weights(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= "a" | "b"`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 20 {
+		s, err := g.Generate(r, bnf.GenerateOptions{Weights: map[string][]float64{"S": {0, 1}}})
+		assert.NoError(t, err)
+		assert.Equal(t, "b", s)
+	}
+
+	_, err = g.Generate(r, bnf.GenerateOptions{Weights: map[string][]float64{"S": {1}}})
+	assert.Error(t, err)
+}
+
+func TestGenerate_Regex(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<assignment> ::= /[a-z]+/ "=" /\d{1,3}/`)
+	assert.NoError(t, err)
+
+	re := regexp.MustCompile(`^[a-z]+=\d{1,3}$`)
+	r := rand.New(rand.NewPCG(5, 6))
+	for range 20 {
+		s, err := g.Generate(r, bnf.GenerateOptions{})
+		assert.NoError(t, err)
+		assert.Regexp(t, re, s)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= "a" <S>`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 1))
+	_, err = g.Generate(r, bnf.GenerateOptions{})
+	assert.ErrorContains(t, err, "no finite derivation")
+
+	_, err = g.Generate(r, bnf.GenerateOptions{Start: "missing"})
+	assert.ErrorContains(t, err, "unknown start rule")
+}
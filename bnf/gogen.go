@@ -0,0 +1,324 @@
+package bnf
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// GoOptions configures the parser generated by GenerateGo.
+type GoOptions struct {
+	Package string // package name of the generated file (default "parser")
+	Source  string // grammar file name mentioned in the generated header
+	Types   bool   // also generate typed AST structs and converters, see GenerateGoTypes
+}
+
+type goGen struct {
+	g       *Grammar
+	funcs   map[string]string // rule name -> Go method name
+	used    map[string]bool   // taken Go identifiers
+	regexps []string          // patterns of the generated regexp variables
+	body    bytes.Buffer      // generated methods
+	rule    string            // Go name of the rule being generated
+	counter int               // helper methods of the current rule
+	skip    string            // method of the @skip skipper, empty without one
+}
+
+// GenerateGo writes a self-contained Go parser for the grammar. The generated
+// file has no dependencies outside of the standard library and exposes Parse,
+// Match and ParseFrom together with ASTNode and ParseError types equivalent to
+// this package's, so it can replace LoadGrammarFile on hot paths and be kept
+// up to date with go generate. Every rule becomes a memoized method and left
+// recursion is handled the same way as by the matching engine.
+func (g *Grammar) GenerateGo(w io.Writer, opts GoOptions) error {
+	if err := g.ValidateGrammar(); err != nil {
+		return err
+	}
+	if opts.Package == "" {
+		opts.Package = "parser"
+	}
+
+	cg := newGoGen(g)
+	names := g.ruleNames()
+	for _, name := range names {
+		cg.funcs[name] = cg.ident("rule" + goName(name))
+	}
+
+	if g.skip != nil {
+		cg.skip = cg.ident("exprSkip")
+		cg.rule = "Skip"
+		if err := cg.method(cg.skip, g.skip); err != nil {
+			return err
+		}
+	}
+	var tokens []string
+	if g.scanner != nil {
+		cg.rule = "Token"
+		for _, def := range g.scanner.defs {
+			f, err := cg.fn(def.node)
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, fmt.Sprintf("\t{kind: %q, rule: %t, match: %s},\n", def.kind, def.rule, f))
+		}
+	}
+	for id, name := range names {
+		cg.rule = goName(name)
+		cg.counter = 0
+		expr := cg.ident("expr" + cg.rule)
+		fmt.Fprintf(&cg.body, "\nfunc (p *parser) %s(pos int) []result {\n\treturn p.rule(%d, %q, pos, (*parser).%s)\n}\n", cg.funcs[name], id, name, expr)
+		if err := cg.method(expr, g.Rules[name].Expr); err != nil {
+			return err
+		}
+	}
+
+	var out bytes.Buffer
+	header := "bnf gen-go"
+	if opts.Source != "" {
+		header += " from " + opts.Source
+	}
+	fmt.Fprintf(&out, "// Code generated by %s. DO NOT EDIT.\n\npackage %s\n\n", header, opts.Package)
+	out.WriteString("import (\n\t\"fmt\"\n\t\"regexp\"\n\t\"strings\"\n\t\"unicode/utf8\"\n)\n\n")
+	fmt.Fprintf(&out, "const startRule = %q\n\n", g.Start)
+	out.WriteString("var rules = map[string]func(*parser, int) []result{\n")
+	for _, name := range names {
+		fmt.Fprintf(&out, "\t%q: (*parser).%s,\n", name, cg.funcs[name])
+	}
+	out.WriteString("}\n")
+	if cg.skip != "" {
+		fmt.Fprintf(&out, "\nvar skipInput = (*parser).%s\n", cg.skip)
+	} else {
+		out.WriteString("\nvar skipInput func(*parser, int) []result\n")
+	}
+	if len(tokens) > 0 {
+		out.WriteString("\nvar tokenDefs = []tokenDef{\n" + strings.Join(tokens, "") + "}\n")
+	} else {
+		out.WriteString("\nvar tokenDefs []tokenDef\n")
+	}
+	if len(cg.regexps) > 0 {
+		out.WriteString("\nvar (\n")
+		for i, re := range cg.regexps {
+			fmt.Fprintf(&out, "\tre%d = regexp.MustCompile(%q)\n", i+1, re)
+		}
+		out.WriteString(")\n")
+	}
+	out.WriteString(goRuntime)
+	out.Write(cg.body.Bytes())
+	if opts.Types {
+		if err := cg.types(&out); err != nil {
+			return err
+		}
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code is invalid: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func newGoGen(g *Grammar) *goGen {
+	cg := &goGen{
+		g:     g,
+		funcs: map[string]string{},
+		used:  map[string]bool{},
+	}
+	// exported names of the runtime
+	for _, name := range []string{"ASTNode", "ParseError", "Parse", "Match", "ParseFrom"} {
+		cg.used[name] = true
+	}
+	return cg
+}
+
+// method generates a parser method named name matching n at pos.
+func (cg *goGen) method(name string, n node) error {
+	var sb strings.Builder
+	switch t := n.(type) {
+	case *sequence:
+		sb.WriteString("\tcurrent := []result{{end: pos}}\n")
+		skip, err := cg.skipFn(t.Skip)
+		if err != nil {
+			return err
+		}
+		for i, e := range t.Elements {
+			f, err := cg.fn(e)
+			if err != nil {
+				return err
+			}
+			if i > 0 && skip != "nil" {
+				fmt.Fprintf(&sb, "\tcurrent = p.then(current, %s)\n", skip)
+			}
+			fmt.Fprintf(&sb, "\tif current = p.then(current, %s); len(current) == 0 {\n\t\treturn nil\n\t}\n", f)
+		}
+		sb.WriteString("\treturn current\n")
+	case *choice:
+		sb.WriteString("\tvar out []result\n")
+		for _, o := range t.Options {
+			call, err := cg.call(o)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&sb, "\tout = append(out, %s...)\n", call)
+		}
+		sb.WriteString("\treturn out\n")
+	case *repeat:
+		f, err := cg.fn(t.Node)
+		if err != nil {
+			return err
+		}
+		skip, err := cg.skipFn(t.Skip)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "\treturn p.repeat(pos, %d, %s, %s)\n", t.Min, f, skip)
+	case *optional:
+		f, err := cg.fn(t.Node)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "\treturn p.optional(pos, %s)\n", f)
+	default:
+		call, err := cg.call(n)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "\treturn %s\n", call)
+	}
+	fmt.Fprintf(&cg.body, "\nfunc (p *parser) %s(pos int) []result {\n%s}\n", name, sb.String())
+	return nil
+}
+
+// call returns a Go expression evaluating to the matches of n at pos.
+func (cg *goGen) call(n node) (string, error) {
+	switch t := n.(type) {
+	case *terminal:
+		return fmt.Sprintf("p.literal(pos, %q)", t.Value), nil
+	case *caselessTerminal:
+		return fmt.Sprintf("p.literalFold(pos, %q)", t.Value), nil
+	case *Regex:
+		cg.regexps = append(cg.regexps, t.Re.String())
+		return fmt.Sprintf("p.regex(pos, re%d)", len(cg.regexps)), nil
+	case *anyRune:
+		return "p.anyRune(pos)", nil
+	case *endOfInput:
+		return "p.end(pos)", nil
+	case *notPredicate:
+		f, err := cg.fn(t.Node)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("p.not(pos, %s)", f), nil
+	case *difference:
+		f, err := cg.fn(t.Node)
+		if err != nil {
+			return "", err
+		}
+		except, err := cg.fn(t.Except)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("p.except(pos, %s, %s)", f, except), nil
+	case *dropped:
+		f, err := cg.fn(t.Node)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("p.drop(pos, %s)", f), nil
+	case *skipper:
+		f, err := cg.fn(t.Node)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("p.skip(pos, %s)", f), nil
+	case *token:
+		for i := range cg.g.scanner.defs {
+			if &cg.g.scanner.defs[i] == t.def {
+				return fmt.Sprintf("p.token(pos, %d)", i), nil
+			}
+		}
+	case regexer:
+		return cg.call(t.regex())
+	case *nonTerminal:
+		call := fmt.Sprintf("p.%s(pos)", cg.funcs[t.Name])
+		switch t.Rule.tree {
+		case treeInline:
+			return "inline(" + call + ")", nil
+		case treeHidden:
+			return "hide(" + call + ")", nil
+		case treeLeaf:
+			return "p.leaf(" + call + ")", nil
+		}
+		return call, nil
+	case *sequence, *choice, *repeat, *optional:
+		f, err := cg.helper(n)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("p.%s(pos)", f), nil
+	}
+	return "", fmt.Errorf("gen-go does not support node %T", n)
+}
+
+// skipFn returns the method expression of a skipper, or nil for none.
+func (cg *goGen) skipFn(skip node) (string, error) {
+	if skip == nil {
+		return "nil", nil
+	}
+	if skip == cg.g.skip {
+		return "(*parser)." + cg.skip, nil
+	}
+	return cg.fn(skip)
+}
+
+// fn returns a Go method expression of type func(*parser, int) []result matching n.
+func (cg *goGen) fn(n node) (string, error) {
+	if nt, ok := n.(*nonTerminal); ok && nt.Rule.tree == treeNode {
+		return "(*parser)." + cg.funcs[nt.Name], nil
+	}
+	f, err := cg.helper(n)
+	if err != nil {
+		return "", err
+	}
+	return "(*parser)." + f, nil
+}
+
+func (cg *goGen) helper(n node) (string, error) {
+	cg.counter++
+	name := cg.ident(fmt.Sprintf("expr%s%d", cg.rule, cg.counter))
+	return name, cg.method(name, n)
+}
+
+// ident reserves a unique Go identifier based on name.
+func (cg *goGen) ident(name string) string {
+	out := name
+	for i := 2; cg.used[out]; i++ {
+		out = fmt.Sprintf("%s_%d", name, i)
+	}
+	cg.used[out] = true
+	return out
+}
+
+// goName converts a rule name like "postal-address" into "PostalAddress".
+func goName(rule string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range rule {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 || !unicode.IsLetter([]rune(sb.String())[0]) {
+		return "R" + sb.String()
+	}
+	return sb.String()
+}
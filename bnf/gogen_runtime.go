@@ -0,0 +1,520 @@
+package bnf
+
+// goRuntime is the grammar independent part of parsers generated by GenerateGo.
+// It mirrors the matching engine: every function returns all possible matches,
+// rules are memoized per position and left recursion is handled by growing a seed.
+const goRuntime = `
+// ASTNode represents a node in the parsed output tree (the Parse Tree).
+type ASTNode struct {
+	Type     string     // Rule name, "TERMINAL", or "REGEX"
+	Value    string     // The actual text matched
+	Children []*ASTNode // Child nodes
+	Pos      int        // Byte offset where the match starts
+	End      int        // Byte offset where the match ends
+}
+
+// String returns a multi-line, indented string representation of the parse tree.
+func (n *ASTNode) String() string {
+	var sb strings.Builder
+	n.format(&sb, 0)
+	return sb.String()
+}
+
+func (n *ASTNode) format(sb *strings.Builder, level int) {
+	indent := strings.Repeat("  ", level)
+
+	if n.Type == "TERMINAL" || n.Type == "REGEX" {
+		sb.WriteString(indent)
+		sb.WriteString(fmt.Sprintf("%q", n.Value))
+		return
+	}
+
+	sb.WriteString(indent)
+	sb.WriteString("(")
+	sb.WriteString(n.Type)
+
+	if len(n.Children) == 0 {
+		if n.Value != "" {
+			sb.WriteString(" ")
+			sb.WriteString(fmt.Sprintf("%q", n.Value))
+		}
+		sb.WriteString(")")
+		return
+	}
+
+	for _, child := range n.Children {
+		sb.WriteString("\n")
+		child.format(sb, level+1)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(indent)
+	sb.WriteString(")")
+}
+
+// ParseError records the details of a failed parse attempt, including the position and expectations.
+type ParseError struct {
+	Pos    int
+	Line   int
+	Column int
+
+	RuleStack []string
+
+	Expected []string
+	Found    string
+
+	Width int // number of characters to highlight
+}
+
+// Error returns a basic string description of the parse error.
+func (err *ParseError) Error() string {
+	return fmt.Sprintf(
+		"  Parse error at line %d, col %d\n  While matching rule: %s\n  Expected: %v\n  Found: %s\n",
+		err.Line, err.Column, err.RuleStack, err.Expected, err.Found,
+	)
+}
+
+// Parse parses the whole input from the start rule and returns its parse tree.
+func Parse(input string) (*ASTNode, error) {
+	return ParseFrom(startRule, input)
+}
+
+// Match checks if the entire input matches the start rule.
+func Match(input string) (bool, error) {
+	_, err := Parse(input)
+	return err == nil, err
+}
+
+// ParseFrom parses the whole input from the given rule and returns its parse tree.
+func ParseFrom(rule string, input string) (*ASTNode, error) {
+	fn, ok := rules[rule]
+	if !ok {
+		return nil, fmt.Errorf("unknown start rule: %s", rule)
+	}
+	p := &parser{
+		input:  input,
+		memo:   map[memoKey][]result{},
+		active: map[int]int{},
+		tokens: map[int]token{},
+	}
+	start := 0
+	if skipInput != nil {
+		start = skipInput(p, 0)[0].end
+	}
+	matches := fn(p, start)
+	if p.err != nil {
+		return nil, p.err
+	}
+	for _, m := range matches {
+		end := m.end
+		if skipInput != nil {
+			end = skipInput(p, end)[0].end
+		}
+		if end == len(input) {
+			return m.nodes[0], nil
+		}
+	}
+	return nil, p.parseError()
+}
+
+const maxGrowthIterations = 1000
+
+type result struct {
+	end   int
+	nodes []*ASTNode
+}
+
+type memoKey struct {
+	rule int
+	pos  int
+}
+
+type parser struct {
+	input  string
+	memo   map[memoKey][]result
+	active map[int]int
+	stack  []string
+	err    error
+	tokens map[int]token // by position, in grammars with @token definitions
+
+	farthest  int
+	failed    bool
+	expected  []string
+	ruleStack []string
+}
+
+// rule memoizes body at pos, grows left-recursive matches and wraps them into rule nodes.
+func (p *parser) rule(id int, name string, pos int, body func(*parser, int) []result) []result {
+	key := memoKey{id, pos}
+	if cached, ok := p.memo[key]; ok {
+		return cached
+	}
+	if p.err != nil {
+		return nil
+	}
+
+	p.memo[key] = nil // failing seed for left recursion
+	p.active[pos]++
+	p.stack = append(p.stack, name)
+
+	var last []result
+	current := wrap(name, pos, body(p, pos))
+	for i := 0; !endsEqual(current, last); i++ {
+		if i >= maxGrowthIterations {
+			p.err = fmt.Errorf("grammar complexity limit exceeded: left-recursive rule %s grew for %d iterations at position %d", name, i, pos)
+			current = nil
+			break
+		}
+		last = current
+		p.memo[key] = last
+		current = wrap(name, pos, body(p, pos))
+	}
+
+	if len(current) == 0 {
+		p.fail(pos, name)
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+
+	p.active[pos]--
+	if p.active[pos] > 0 {
+		// the result may still depend on a growing seed of an enclosing rule
+		delete(p.memo, key)
+	} else {
+		p.memo[key] = current
+	}
+	return current
+}
+
+// wrap turns the matches of a rule body into matches of a single rule node.
+func wrap(name string, pos int, matches []result) []result {
+	out := make([]result, len(matches))
+	for i, m := range matches {
+		out[i] = result{end: m.end, nodes: []*ASTNode{{Type: name, Children: m.nodes, Pos: pos, End: m.end}}}
+	}
+	return out
+}
+
+// inline puts the children of the node of every match of a rule in its place.
+func inline(matches []result) []result {
+	out := make([]result, len(matches))
+	for i, m := range matches {
+		out[i] = result{end: m.end, nodes: m.nodes[0].Children}
+	}
+	return out
+}
+
+// hide removes the node of every match of a rule.
+func hide(matches []result) []result {
+	var out []result
+	for _, m := range matches {
+		if !endsAt(out, m.end) {
+			out = append(out, result{end: m.end})
+		}
+	}
+	return out
+}
+
+// leaf replaces the node of every match of a rule with a node without
+// children, the matched text as its Value.
+func (p *parser) leaf(matches []result) []result {
+	var out []result
+	for _, m := range matches {
+		if !endsAt(out, m.end) {
+			n := m.nodes[0]
+			out = append(out, result{end: m.end, nodes: []*ASTNode{{Type: n.Type, Value: p.input[n.Pos:n.End], Pos: n.Pos, End: n.End}}})
+		}
+	}
+	return out
+}
+
+func endsAt(matches []result, end int) bool {
+	for _, m := range matches {
+		if m.end == end {
+			return true
+		}
+	}
+	return false
+}
+
+func endsEqual(a, b []result) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].end != b[i].end {
+			return false
+		}
+	}
+	return true
+}
+
+// then extends every match in current with the matches of f.
+func (p *parser) then(current []result, f func(*parser, int) []result) []result {
+	var next []result
+	for _, c := range current {
+		for _, m := range f(p, c.end) {
+			next = append(next, result{end: m.end, nodes: joinNodes(c.nodes, m.nodes)})
+		}
+	}
+	return next
+}
+
+// repeat matches f min or more times, with skip between the iterations
+// unless it is nil.
+func (p *parser) repeat(pos int, min int, f, skip func(*parser, int) []result) []result {
+	current := []result{{end: pos}}
+	var out []result
+	for i := 0; ; i++ {
+		if i >= min {
+			out = append(out, current...)
+		}
+		var next []result
+		for _, c := range current {
+			start := c.end
+			if i > 0 && skip != nil {
+				start = skip(p, start)[0].end
+			}
+			for _, m := range f(p, start) {
+				if m.end > c.end {
+					next = append(next, result{end: m.end, nodes: joinNodes(c.nodes, m.nodes)})
+				}
+			}
+		}
+		if len(next) == 0 {
+			return out
+		}
+		current = next
+	}
+}
+
+func (p *parser) optional(pos int, f func(*parser, int) []result) []result {
+	out := []result{{end: pos}}
+	for _, m := range f(p, pos) {
+		if m.end != pos {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (p *parser) literal(pos int, s string) []result {
+	if strings.HasPrefix(p.input[pos:], s) {
+		return []result{{end: pos + len(s), nodes: []*ASTNode{{Type: "TERMINAL", Value: s, Pos: pos, End: pos + len(s)}}}}
+	}
+	p.fail(pos, fmt.Sprintf("%q", s))
+	return nil
+}
+
+func (p *parser) literalFold(pos int, s string) []result {
+	end := pos
+	for _, want := range s {
+		_, size := utf8.DecodeRuneInString(p.input[end:])
+		if size == 0 || !strings.EqualFold(p.input[end:end+size], string(want)) {
+			p.fail(pos, fmt.Sprintf("%qi", s))
+			return nil
+		}
+		end += size
+	}
+	return []result{{end: end, nodes: []*ASTNode{{Type: "TERMINAL", Value: p.input[pos:end], Pos: pos, End: end}}}}
+}
+
+func (p *parser) regex(pos int, re *regexp.Regexp) []result {
+	loc := re.FindStringIndex(p.input[pos:])
+	if loc != nil && loc[0] == 0 {
+		return []result{{end: pos + loc[1], nodes: []*ASTNode{{Type: "REGEX", Value: p.input[pos : pos+loc[1]], Pos: pos, End: pos + loc[1]}}}}
+	}
+	p.fail(pos, re.String())
+	return nil
+}
+
+func (p *parser) anyRune(pos int) []result {
+	_, size := utf8.DecodeRuneInString(p.input[pos:])
+	if size == 0 {
+		p.fail(pos, "any character")
+		return nil
+	}
+	return []result{{end: pos + size, nodes: []*ASTNode{{Type: "REGEX", Value: p.input[pos : pos+size], Pos: pos, End: pos + size}}}}
+}
+
+func (p *parser) end(pos int) []result {
+	if pos < len(p.input) {
+		p.fail(pos, "EOF")
+		return nil
+	}
+	return []result{{end: pos}}
+}
+
+// not matches the empty string when f does not match at pos.
+func (p *parser) not(pos int, f func(*parser, int) []result) []result {
+	if len(p.lookahead(pos, f)) > 0 {
+		return nil
+	}
+	return []result{{end: pos}}
+}
+
+// except returns the matches of f at pos, but those spanning the same text as a match of g.
+func (p *parser) except(pos int, f, g func(*parser, int) []result) []result {
+	matches := f(p, pos)
+	if len(matches) == 0 {
+		return nil
+	}
+	ends := map[int]bool{}
+	for _, m := range p.lookahead(pos, g) {
+		ends[m.end] = true
+	}
+	var out []result
+	for _, m := range matches {
+		if !ends[m.end] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// skip matches f at pos as often as it can, taking its longest match each
+// time, and returns the single position reached.
+func (p *parser) skip(pos int, f func(*parser, int) []result) []result {
+	for {
+		end := pos
+		for _, m := range p.lookahead(pos, f) {
+			end = max(end, m.end)
+		}
+		if end == pos {
+			return []result{{end: pos}}
+		}
+		pos = end
+	}
+}
+
+// drop returns the matches of f at pos without their nodes.
+func (p *parser) drop(pos int, f func(*parser, int) []result) []result {
+	seen := map[int]bool{}
+	var out []result
+	for _, m := range f(p, pos) {
+		if !seen[m.end] {
+			seen[m.end] = true
+			out = append(out, result{end: m.end})
+		}
+	}
+	return out
+}
+
+// tokenDef is a kind of token: a literal of a syntactic rule or a @token rule.
+type tokenDef struct {
+	kind  string
+	rule  bool
+	match func(*parser, int) []result
+}
+
+type token struct {
+	def, pos, end int
+}
+
+// scan returns the token following pos with nothing but skipped text between,
+// the longest one and on a tie the one of the earlier definition. Its def is
+// -1 when no token matches.
+func (p *parser) scan(pos int) token {
+	if t, ok := p.tokens[pos]; ok {
+		return t
+	}
+	start := pos
+	if skipInput != nil {
+		start = skipInput(p, start)[0].end
+	}
+	best := token{def: -1, pos: start, end: start}
+	for i, def := range tokenDefs {
+		for _, m := range p.lookahead(start, def.match) {
+			if m.end > best.end {
+				best = token{def: i, pos: start, end: m.end}
+			}
+		}
+	}
+	p.tokens[pos] = best
+	return best
+}
+
+// token matches the token following pos when it is of the definition def.
+func (p *parser) token(pos int, def int) []result {
+	t := p.scan(pos)
+	if t.def != def {
+		p.fail(pos, tokenDefs[def].kind)
+		return nil
+	}
+	leaf := &ASTNode{Type: "TERMINAL", Value: p.input[t.pos:t.end], Pos: t.pos, End: t.end}
+	if tokenDefs[def].rule {
+		leaf.Type = tokenDefs[def].kind
+	}
+	return []result{{end: t.end, nodes: []*ASTNode{leaf}}}
+}
+
+// lookahead returns the matches of f at pos without recording its failures,
+// which lookaheads and differences hope for rather than report.
+func (p *parser) lookahead(pos int, f func(*parser, int) []result) []result {
+	failed, farthest, expected, ruleStack := p.failed, p.farthest, p.expected, p.ruleStack
+	matches := f(p, pos)
+	p.failed, p.farthest, p.expected, p.ruleStack = failed, farthest, expected, ruleStack
+	return matches
+}
+
+func joinNodes(a, b []*ASTNode) []*ASTNode {
+	out := make([]*ASTNode, len(a)+len(b))
+	copy(out, a)
+	copy(out[len(a):], b)
+	return out
+}
+
+// fail records what was expected at pos, keeping the farthest failure only.
+func (p *parser) fail(pos int, expected string) {
+	switch {
+	case !p.failed || pos > p.farthest:
+		p.failed = true
+		p.farthest = pos
+		p.expected = []string{expected}
+		p.ruleStack = append([]string(nil), p.stack...)
+	case pos == p.farthest:
+		for _, e := range p.expected {
+			if e == expected {
+				return
+			}
+		}
+		p.expected = append(p.expected, expected)
+	}
+}
+
+func (p *parser) parseError() *ParseError {
+	line, col := 1, 1
+	for i, r := range p.input {
+		if i >= p.farthest {
+			break
+		}
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+
+	found := "EOF"
+	if p.farthest < len(p.input) {
+		r, _ := utf8.DecodeRuneInString(p.input[p.farthest:])
+		found = fmt.Sprintf("%q", r)
+	}
+
+	width := 1
+	for _, e := range p.expected {
+		if len(e) >= 2 && e[0] == '"' && e[len(e)-1] == '"' {
+			width = max(width, utf8.RuneCountInString(e[1:len(e)-1]))
+		}
+	}
+
+	return &ParseError{
+		Pos:       p.farthest,
+		Line:      line,
+		Column:    col,
+		RuleStack: p.ruleStack,
+		Expected:  p.expected,
+		Found:     found,
+		Width:     width,
+	}
+}
+`
@@ -0,0 +1,437 @@
+package bnf_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const genMain = `package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"gentest/parser"
+)
+
+func main() {
+	var inputs []string
+	json.NewDecoder(os.Stdin).Decode(&inputs)
+
+	var out []string
+	for _, in := range inputs {
+		tree, err := parser.Parse(in)
+		if err != nil {
+			out = append(out, "error")
+		} else {
+			out = append(out, tree.String())
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+}
+`
+
+// runGenerated compiles the generated parser and parses inputs with it.
+func runGenerated(t *testing.T, src []byte, inputs []string) []string {
+	t.Helper()
+	return runProgram(t, src, genMain, "", inputs)
+}
+
+// runProgram compiles main together with the generated package and runs it
+// with the JSON encoded inputs on stdin, returning its JSON encoded output.
+func runProgram(t *testing.T, src []byte, main string, gomod string, inputs []string) []string {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "parser"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gentest\n\ngo 1.25\n"+gomod), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(main), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "parser", "parser.go"), src, 0o644))
+
+	in, _ := json.Marshal(inputs)
+	cmd := exec.Command(goBin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOTOOLCHAIN=local")
+	cmd.Stdin = bytes.NewReader(in)
+	out, err := cmd.CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		return nil
+	}
+
+	var trees []string
+	assert.NoError(t, json.Unmarshal(out, &trees))
+	return trees
+}
+
+func TestGenerateGo_MatchesEngine(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		grammar string
+		inputs  []string
+	}{
+		{"../examples/hour.bnf", []string{"4pm", "7:38pm", "23:42", "3:16am", "x", "123"}},
+		{"../examples/left-recursive.bnf", []string{"a", "a,b,c", "a,", ""}},
+		{"../examples/numbers.bnf", []string{"0", "120", "1.5", "00", "1,5.2"}},
+		{"../examples/postal.bnf", []string{"John Smith\n123 Main St\nSpringfield, MA 02139\n", "J. Doe\n"}},
+	} {
+		g, err := bnf.LoadGrammarFile(tt.grammar)
+		assert.NoError(t, err)
+
+		var src bytes.Buffer
+		assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Package: "parser", Source: filepath.Base(tt.grammar)}))
+		assert.Contains(t, src.String(), "// Code generated by bnf gen-go from")
+
+		var want []string
+		for _, in := range tt.inputs {
+			tree, err := g.Parse(in)
+			if err != nil {
+				want = append(want, "error")
+			} else {
+				want = append(want, tree.String())
+			}
+		}
+		assert.Equal(t, want, runGenerated(t, src.Bytes(), tt.inputs), tt.grammar)
+	}
+}
+
+func TestGenerateGo_Regex(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<assignment> ::= <name> "=" /\d+/
+<name>       ::= /[a-z]+/
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{}))
+	assert.Contains(t, src.String(), "package parser")
+	assert.Contains(t, src.String(), `regexp.MustCompile("[a-z]+")`)
+	assert.Contains(t, src.String(), "func (p *parser) ruleName(pos int) []result")
+
+	got := runGenerated(t, src.Bytes(), []string{"x=12", "x=", "X=1"})
+	if assert.Len(t, got, 3) {
+		assert.Contains(t, got[0], `"12"`)
+		assert.Equal(t, "error", got[1])
+		assert.Equal(t, "error", got[2])
+	}
+}
+
+func TestGenerateGo_Caseless(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<cmd> ::= ("GET"i | "ΣΊΣΥΦΟΣ"i) " " /[a-z]+/`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{}))
+
+	inputs := []string{"get x", "Get x", "σίσυφος y", "put x"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_Predicates(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<file>    ::= (<comment> | <word>)* $
+<comment> ::= "/*" (!"*/" .)* "*/"
+<word>    ::= !"end" [a-z]+ " "?
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{"/* ż */ab cd", "/**/", "/* */ */", "ab end", "x/*"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_Difference(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<list>    ::= <name> ("," <name>)*
+<name>    ::= <ident> - <keyword>
+<ident>   ::= [a-z]+
+<keyword> ::= "if" | "for"
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{"a,fort", "if", "a,for", "x,"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_Params(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<call>          ::= <ident> "(" <sep-list <ident> ","> ")"
+<sep-list X S>  ::= X (S X)*
+<ident>         ::= [a-z]+
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{"f(a)", "f(a,b,c)", "f()", "f(a,)"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_SepList(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<call> ::= <id> "(" <arg> %%* "," ")"
+<arg>  ::= <id> | <call>
+<id>   ::= [a-z]+
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{"f()", "f(a,g(b,),)", "f(,)"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_Skip(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+@skip ::= /[ \t\n]+/ | comment
+list    ::= "(" item* ")"
+item    ::= NAME | list
+NAME    ::= [a-z]+
+comment ::= ";" (!"\n" .)*
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{" (a (b c) ; note\n d ) ", "(ab)", "(a b", "(a ; b)"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_Tokens(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+@skip ::= /\s+/
+program ::= stmt*
+stmt    ::= "let" IDENT "=" expr ";" | "if" expr "==" expr ";"
+expr    ::= IDENT | NUMBER
+@token IDENT  ::= /[a-z]+/
+@token NUMBER ::= /[0-9]+/
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{"let letter = 1; if a == b;", "let let = 1;", "if a = b;", "let x = 1 ?"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_Annotations(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+list          ::= "(" items ")"
+@inline items ::= item (-"," _ws item)*
+item          ::= word | list | num
+@leaf word    ::= [a-z]+
+@hidden num   ::= [0-9]+
+_ws           ::= " "*
+`)
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+
+	inputs := []string{"(a, (bc,1),d)", "(a,)", "()"}
+	var want []string
+	for _, in := range inputs {
+		tree, err := g.Parse(in)
+		if err != nil {
+			want = append(want, "error")
+		} else {
+			want = append(want, tree.String())
+		}
+	}
+	assert.Equal(t, want, runGenerated(t, src.Bytes(), inputs))
+}
+
+func TestGenerateGo_InvalidGrammar(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= <undefined>`)
+	assert.NoError(t, err)
+	assert.Error(t, g.GenerateGo(&bytes.Buffer{}, bnf.GoOptions{}))
+}
+
+const typedMain = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gentest/parser"
+)
+
+// describe renders the typed form of a list back into a bracketed string.
+func describe(l parser.List) string {
+	switch v := l.(type) {
+	case *parser.ListAlt1:
+		return "[" + describe(v.List) + " " + v.Id.Text + "]"
+	case *parser.Id:
+		return v.Text
+	}
+	return fmt.Sprintf("%T", l)
+}
+
+func main() {
+	var inputs []string
+	json.NewDecoder(os.Stdin).Decode(&inputs)
+
+	var out []string
+	for _, in := range inputs {
+		tree, err := parse(in)
+		if err != nil {
+			out = append(out, "error")
+			continue
+		}
+		list, err := parser.ToList(tree)
+		if err != nil {
+			out = append(out, err.Error())
+			continue
+		}
+		out = append(out, describe(list))
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+}
+`
+
+func TestGenerateGo_Types(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/left-recursive.bnf")
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGo(&src, bnf.GoOptions{Types: true}))
+	assert.Contains(t, src.String(), "type List interface {")
+	assert.Contains(t, src.String(), "func ToId(n *ASTNode) (*Id, error)")
+
+	main := typedMain + "\nfunc parse(in string) (*parser.ASTNode, error) { return parser.Parse(in) }\n"
+	got := runProgram(t, src.Bytes(), main, "", []string{"a", "a,b,c", "a,"})
+	assert.Equal(t, []string{"a", "[[a b] c]", "error"}, got)
+}
+
+func TestGenerateGoTypes(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/left-recursive.bnf")
+	assert.NoError(t, err)
+
+	var src bytes.Buffer
+	assert.NoError(t, g.GenerateGoTypes(&src, bnf.GoOptions{Package: "parser"}))
+	assert.Contains(t, src.String(), "type ASTNode = bnf.ASTNode")
+	assert.NotContains(t, src.String(), "func Parse(")
+
+	root, err := filepath.Abs("..")
+	assert.NoError(t, err)
+	gomod := "\nrequire github.com/tgagor/go-bnf v0.0.0\n\nreplace github.com/tgagor/go-bnf => " + root + "\n"
+	main := typedMain + `
+var grammar, _ = bnf.LoadGrammarFile(` + fmt.Sprintf("%q", filepath.Join(root, "examples", "left-recursive.bnf")) + `)
+
+func parse(in string) (*parser.ASTNode, error) { return grammar.Parse(in) }
+`
+	main = strings.Replace(main, `"gentest/parser"`, `"gentest/parser"`+"\n\n\t\"github.com/tgagor/go-bnf/bnf\"", 1)
+	got := runProgram(t, src.Bytes(), main, gomod, []string{"a,b", "c"})
+	assert.Equal(t, []string{"[a b]", "c"}, got)
+}
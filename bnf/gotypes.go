@@ -0,0 +1,396 @@
+package bnf
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"slices"
+	"strings"
+)
+
+// GenerateGoTypes writes Go types for the parse trees of the grammar together
+// with converters from *bnf.ASTNode, for programs parsing with this package at
+// run time. Use GoOptions.Types to add the same types to a parser generated by
+// GenerateGo instead.
+//
+// Every rule becomes a struct with a field per sub-rule it references: a value
+// when the sub-rule appears exactly once, a pointer when it is optional and a
+// slice when it may repeat. A rule whose alternatives reference sub-rules
+// becomes an interface implemented by a struct per alternative, or directly by
+// the sub-rule's struct when the alternative is a lone reference. Converters are
+// named after the types, e.g. ToPostalAddress.
+func (g *Grammar) GenerateGoTypes(w io.Writer, opts GoOptions) error {
+	if err := g.ValidateGrammar(); err != nil {
+		return err
+	}
+	if opts.Package == "" {
+		opts.Package = "parser"
+	}
+
+	cg := newGoGen(g)
+	var out bytes.Buffer
+	header := "bnf gen-go"
+	if opts.Source != "" {
+		header += " from " + opts.Source
+	}
+	fmt.Fprintf(&out, "// Code generated by %s. DO NOT EDIT.\n\npackage %s\n\n", header, opts.Package)
+	out.WriteString("import (\n\t\"fmt\"\n\t\"strings\"\n\n\t\"github.com/tgagor/go-bnf/bnf\"\n)\n\n")
+	out.WriteString("// ASTNode is the parse tree node the typed forms are converted from.\ntype ASTNode = bnf.ASTNode\n")
+	if err := cg.types(&out); err != nil {
+		return err
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code is invalid: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// occurrence bounds how often a sub-rule appears in a match of an expression,
+// a max of 2 meaning any number of times.
+type occurrence struct {
+	min, max int
+}
+
+// occurrences returns the sub-rules referenced by n in order of first appearance
+// together with how often each of them appears in a match.
+func occurrences(n node) ([]string, map[string]occurrence) {
+	switch t := n.(type) {
+	case *nonTerminal:
+		switch t.Rule.tree {
+		case treeInline:
+			return occurrences(t.Rule.Expr)
+		case treeHidden:
+			return nil, nil
+		}
+		return []string{t.Name}, map[string]occurrence{t.Name: {1, 1}}
+	case *sequence:
+		var names []string
+		occ := map[string]occurrence{}
+		for _, e := range t.Elements {
+			en, eo := occurrences(e)
+			for _, name := range en {
+				o, seen := occ[name]
+				if !seen {
+					names = append(names, name)
+				}
+				occ[name] = occurrence{o.min + eo[name].min, min(2, o.max+eo[name].max)}
+			}
+		}
+		return names, occ
+	case *choice:
+		var names []string
+		occ := map[string]occurrence{}
+		opts := make([]map[string]occurrence, len(t.Options))
+		for i, o := range t.Options {
+			var on []string
+			on, opts[i] = occurrences(o)
+			for _, name := range on {
+				if _, seen := occ[name]; !seen {
+					names = append(names, name)
+					occ[name] = occurrence{2, 0}
+				}
+			}
+		}
+		for _, name := range names {
+			o := occ[name]
+			for _, oo := range opts {
+				o = occurrence{min(o.min, oo[name].min), max(o.max, oo[name].max)}
+			}
+			occ[name] = o
+		}
+		return names, occ
+	case *repeat:
+		names, occ := occurrences(t.Node)
+		for name, o := range occ {
+			if t.Min == 0 {
+				o.min = 0
+			}
+			if o.max > 0 {
+				o.max = 2
+			}
+			occ[name] = o
+		}
+		return names, occ
+	case *optional:
+		names, occ := occurrences(t.Node)
+		for name, o := range occ {
+			occ[name] = occurrence{0, o.max}
+		}
+		return names, occ
+	case *difference:
+		return occurrences(t.Node)
+	case *token:
+		return occurrences(t.def.node)
+	}
+	return nil, nil
+}
+
+// recursiveInline returns an @inline rule using itself through @inline rules
+// only, whose children could nest without end, or "" when there is none.
+func recursiveInline(rules map[string]*Rule, names []string) string {
+	for _, name := range names {
+		if rules[name].tree != treeInline {
+			continue
+		}
+		seen := map[string]bool{}
+		var uses func(n node) bool
+		uses = func(n node) bool {
+			switch t := n.(type) {
+			case *nonTerminal:
+				if t.Name == name {
+					return true
+				}
+				if t.Rule.tree != treeInline || seen[t.Name] {
+					return false
+				}
+				seen[t.Name] = true
+				return uses(t.Rule.Expr)
+			case *notPredicate, *dropped:
+				return false // no children
+			}
+			return slices.ContainsFunc(children(n), uses)
+		}
+		if uses(rules[name].Expr) {
+			return name
+		}
+	}
+	return ""
+}
+
+// goStruct describes a generated struct: a rule or an alternative of a rule.
+type goStruct struct {
+	name   string
+	rule   string
+	fields []goField
+}
+
+type goField struct {
+	name string
+	rule string
+	kind byte   // 'v' value, 'p' pointer, 's' slice, 'i' interface
+	elem string // dereference of the converted value: "*" for structs
+}
+
+// types writes a struct per rule, or an interface when the rule is a choice of
+// alternatives carrying sub-rules, and the converters from ASTNode into them.
+func (cg *goGen) types(out *bytes.Buffer) error {
+	names := cg.g.ruleNames()
+	if name := recursiveInline(cg.g.Rules, names); name != "" {
+		return fmt.Errorf("gen-go types do not support the recursive @inline rule %s", name)
+	}
+	typeName := map[string]string{}
+	iface := map[string]bool{}
+	for _, name := range names {
+		typeName[name] = cg.ident(goName(name))
+		if c, ok := cg.g.Rules[name].Expr.(*choice); ok && cg.g.Rules[name].tree != treeLeaf {
+			if refs, _ := occurrences(c); len(refs) > 0 {
+				iface[name] = true
+			}
+		}
+	}
+
+	// values embed each other, so they must not form cycles
+	embeds := map[string][]string{}
+	var reaches func(from, to string, seen map[string]bool) bool
+	reaches = func(from, to string, seen map[string]bool) bool {
+		if from == to {
+			return true
+		}
+		seen[from] = true
+		for _, next := range embeds[from] {
+			if !seen[next] && reaches(next, to, seen) {
+				return true
+			}
+		}
+		return false
+	}
+	fields := func(owner string, n node) []goField {
+		refs, occ := occurrences(n)
+		used := map[string]bool{"Node": true, "Text": true}
+		var out []goField
+		for _, ref := range refs {
+			f := goField{name: goName(ref), rule: ref}
+			for i := 2; used[f.name]; i++ {
+				f.name = fmt.Sprintf("%s%d", goName(ref), i)
+			}
+			used[f.name] = true
+			if !iface[ref] {
+				f.elem = "*"
+			}
+			switch o := occ[ref]; {
+			case o.max > 1:
+				f.kind = 's'
+			case iface[ref]:
+				f.kind = 'i'
+			case o.min == 0 || reaches(ref, owner, map[string]bool{}):
+				f.kind = 'p'
+			default:
+				f.kind = 'v'
+				embeds[owner] = append(embeds[owner], ref)
+			}
+			out = append(out, f)
+		}
+		return out
+	}
+
+	var body bytes.Buffer
+	for _, name := range names {
+		expr := cg.g.Rules[name].Expr
+		if cg.g.Rules[name].tree == treeLeaf {
+			expr = &sequence{} // nodes of the rule have no children
+		}
+		tn := typeName[name]
+		conv := "conv" + tn
+		to := cg.ident("To" + tn)
+
+		if !iface[name] {
+			s := goStruct{name: tn, rule: name, fields: fields(name, expr)}
+			fmt.Fprintf(&body, "\n// %s is the typed form of rule <%s>.\n", tn, name)
+			writeStruct(&body, s, typeName)
+			fmt.Fprintf(&body, "\n// %s converts a parse tree node of rule <%s> into its typed form.\n", to, name)
+			fmt.Fprintf(&body, "func %s(n *ASTNode) (*%s, error) {\n\tvar c converter\n\tv := c.%s(n)\n\treturn v, c.err\n}\n", to, tn, conv)
+			fmt.Fprintf(&body, "\nfunc (c *converter) %s(n *ASTNode) *%s {\n\tv := &%s{Node: n}\n\tif !c.check(n, %q) {\n\t\treturn v\n\t}\n", conv, tn, tn, name)
+			writeFill(&body, s, typeName)
+			body.WriteString("\treturn v\n}\n")
+			continue
+		}
+
+		options := expr.(*choice).Options
+		marker := "is" + tn
+		fmt.Fprintf(&body, "\n// %s is the typed form of rule <%s>, one of the types of its alternatives.\n", tn, name)
+		fmt.Fprintf(&body, "type %s interface {\n\t%s()\n}\n", tn, marker)
+
+		shapes := cg.ident("shapes" + tn)
+		fmt.Fprintf(&body, "\nvar %s = [...]shape{\n", shapes)
+		for _, o := range options {
+			lit, err := shapeLit(o)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&body, "\t%s,\n", lit)
+		}
+		body.WriteString("}\n")
+
+		var cases strings.Builder
+		for i, o := range options {
+			fmt.Fprintf(&cases, "\tcase %s[%d].matches(n.Children):\n", shapes, i)
+			if nt, ok := o.(*nonTerminal); ok && !iface[nt.Name] && (nt.Rule.tree == treeNode || nt.Rule.tree == treeLeaf) {
+				fmt.Fprintf(&body, "\nfunc (*%s) %s() {}\n", typeName[nt.Name], marker)
+				fmt.Fprintf(&cases, "\t\treturn c.conv%s(n.Children[0])\n", typeName[nt.Name])
+				continue
+			}
+
+			alt := cg.ident(fmt.Sprintf("%sAlt%d", tn, i+1))
+			s := goStruct{name: alt, rule: name, fields: fields(name, o)}
+			fmt.Fprintf(&body, "\n// %s is alternative %d of rule <%s>: %s\n", alt, i+1, name, formatNode(o))
+			writeStruct(&body, s, typeName)
+			fmt.Fprintf(&body, "\nfunc (*%s) %s() {}\n", alt, marker)
+			fmt.Fprintf(&cases, "\t\tv := &%s{Node: n}\n", alt)
+			writeFill(&cases, s, typeName)
+			cases.WriteString("\t\treturn v\n")
+		}
+
+		fmt.Fprintf(&body, "\n// %s converts a parse tree node of rule <%s> into its typed form.\n", to, name)
+		fmt.Fprintf(&body, "func %s(n *ASTNode) (%s, error) {\n\tvar c converter\n\tv := c.%s(n)\n\treturn v, c.err\n}\n", to, tn, conv)
+		fmt.Fprintf(&body, "\nfunc (c *converter) %s(n *ASTNode) %s {\n\tif !c.check(n, %q) {\n\t\treturn nil\n\t}\n\tswitch {\n%s\t}\n", conv, tn, name, cases.String())
+		body.WriteString("\tc.fail(n, \"no alternative of rule <%s> matches the children\", n.Type)\n\treturn nil\n}\n")
+	}
+
+	out.WriteString(goTypesRuntime)
+	out.Write(body.Bytes())
+	return nil
+}
+
+func writeStruct(w io.Writer, s goStruct, typeName map[string]string) {
+	fmt.Fprintf(w, "type %s struct {\n\tNode *ASTNode // parse tree node of the match\n\tText string   // matched text\n", s.name)
+	for _, f := range s.fields {
+		tn := typeName[f.rule]
+		switch f.kind {
+		case 's':
+			fmt.Fprintf(w, "\t%s []%s\n", f.name, tn)
+		case 'p':
+			fmt.Fprintf(w, "\t%s *%s\n", f.name, tn)
+		default:
+			fmt.Fprintf(w, "\t%s %s\n", f.name, tn)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeFill writes the statements filling v from the children of n.
+func writeFill(w io.Writer, s goStruct, typeName map[string]string) {
+	fmt.Fprintln(w, "\tv.Text = text(n)")
+	if len(s.fields) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\tfor _, child := range n.Children {\n\t\tswitch child.Type {")
+	for _, f := range s.fields {
+		conv := "c.conv" + typeName[f.rule] + "(child)"
+		fmt.Fprintf(w, "\t\tcase %q:\n", f.rule)
+		switch f.kind {
+		case 's':
+			fmt.Fprintf(w, "\t\t\tv.%s = append(v.%s, %s%s)\n", f.name, f.name, f.elem, conv)
+		case 'v':
+			fmt.Fprintf(w, "\t\t\tv.%s = *%s\n", f.name, conv)
+		default:
+			fmt.Fprintf(w, "\t\t\tv.%s = %s\n", f.name, conv)
+		}
+	}
+	fmt.Fprintln(w, "\t\t}\n\t}")
+}
+
+// shapeLit renders n as a shape literal the converters use to tell the
+// alternatives of a rule apart by the children of its node.
+func shapeLit(n node) (string, error) {
+	items := func(nodes ...node) (string, error) {
+		parts := make([]string, len(nodes))
+		for i, e := range nodes {
+			lit, err := shapeLit(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = lit
+		}
+		return "[]shape{" + strings.Join(parts, ", ") + "}", nil
+	}
+
+	switch t := n.(type) {
+	case *terminal:
+		return fmt.Sprintf("{kind: 'l', value: %q}", t.Value), nil
+	case *caselessTerminal:
+		return fmt.Sprintf("{kind: 'i', value: %q}", t.Value), nil
+	case *Regex, regexer:
+		return "{kind: 'r'}", nil
+	case *endOfInput, *notPredicate, *dropped:
+		return "{kind: 's'}", nil // no children
+	case *difference:
+		return shapeLit(t.Node)
+	case *token:
+		return shapeLit(t.def.node)
+	case *nonTerminal:
+		switch t.Rule.tree {
+		case treeInline:
+			return shapeLit(t.Rule.Expr)
+		case treeHidden:
+			return "{kind: 's'}", nil
+		}
+		return fmt.Sprintf("{kind: 'n', value: %q}", t.Name), nil
+	case *sequence:
+		s, err := items(t.Elements...)
+		return "{kind: 's', items: " + s + "}", err
+	case *choice:
+		s, err := items(t.Options...)
+		return "{kind: 'c', items: " + s + "}", err
+	case *repeat:
+		s, err := items(t.Node)
+		return fmt.Sprintf("{kind: '*', min: %d, items: %s}", t.Min, s), err
+	case *optional:
+		s, err := items(t.Node)
+		return "{kind: '?', items: " + s + "}", err
+	}
+	return "", fmt.Errorf("gen-go does not support node %T", n)
+}
@@ -0,0 +1,104 @@
+package bnf
+
+// goTypesRuntime is the grammar independent part of the typed AST converters
+// generated by GenerateGoTypes and GenerateGo with GoOptions.Types.
+const goTypesRuntime = `
+type converter struct {
+	err error
+}
+
+// check reports whether n is a node of the rule, recording an error otherwise.
+func (c *converter) check(n *ASTNode, rule string) bool {
+	if n == nil {
+		c.fail(n, "expected a node of rule <%s>, got nil", rule)
+		return false
+	}
+	if n.Type != rule {
+		c.fail(n, "expected a node of rule <%s>, got %s", rule, n.Type)
+		return false
+	}
+	return true
+}
+
+// fail records the first conversion error.
+func (c *converter) fail(n *ASTNode, format string, args ...any) {
+	if c.err != nil {
+		return
+	}
+	c.err = fmt.Errorf(format, args...)
+	if n != nil {
+		c.err = fmt.Errorf("at offset %d: %w", n.Pos, c.err)
+	}
+}
+
+// text returns the input matched by n.
+func text(n *ASTNode) string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	var sb strings.Builder
+	for _, child := range n.Children {
+		sb.WriteString(text(child))
+	}
+	return sb.String()
+}
+
+// shape describes the children an alternative of a rule produces.
+type shape struct {
+	kind  byte // 'l' literal, 'i' case-insensitive literal, 'r' regex, 'n' rule, 's' sequence, 'c' choice, '*' repetition, '?' optional
+	value string
+	min   int
+	items []shape
+}
+
+func (s shape) matches(nodes []*ASTNode) bool {
+	return s.match(nodes, 0, func(i int) bool { return i == len(nodes) })
+}
+
+// match calls k with every position the shape can end at when matching nodes from i,
+// stopping as soon as k accepts one.
+func (s shape) match(nodes []*ASTNode, i int, k func(int) bool) bool {
+	switch s.kind {
+	case 'l':
+		return i < len(nodes) && nodes[i].Type == "TERMINAL" && nodes[i].Value == s.value && k(i+1)
+	case 'i':
+		return i < len(nodes) && nodes[i].Type == "TERMINAL" && strings.EqualFold(nodes[i].Value, s.value) && k(i+1)
+	case 'r':
+		return i < len(nodes) && nodes[i].Type == "REGEX" && k(i+1)
+	case 'n':
+		return i < len(nodes) && nodes[i].Type == s.value && k(i+1)
+	case 's':
+		return matchAll(s.items, nodes, i, k)
+	case 'c':
+		for _, o := range s.items {
+			if o.match(nodes, i, k) {
+				return true
+			}
+		}
+		return false
+	case '*':
+		return s.repeat(nodes, i, 0, k)
+	case '?':
+		return k(i) || s.items[0].match(nodes, i, k)
+	}
+	return false
+}
+
+func (s shape) repeat(nodes []*ASTNode, i, count int, k func(int) bool) bool {
+	if count >= s.min && k(i) {
+		return true
+	}
+	return s.items[0].match(nodes, i, func(j int) bool {
+		return (j > i || count < s.min) && s.repeat(nodes, j, count+1, k)
+	})
+}
+
+func matchAll(items []shape, nodes []*ASTNode, i int, k func(int) bool) bool {
+	if len(items) == 0 {
+		return k(i)
+	}
+	return items[0].match(nodes, i, func(j int) bool {
+		return matchAll(items[1:], nodes, j, k)
+	})
+}
+`
@@ -2,8 +2,9 @@ package bnf
 
 import (
 	"fmt"
-	"os"
 	"io"
+	"io/fs"
+	"sort"
 	"strings"
 )
 
@@ -11,15 +12,23 @@ import (
 type Rule struct {
 	Name string
 	Expr node
+
+	tree treeShape // how matches appear in parse trees
 }
 
 // Grammar represents a complete set of BNF rules and an optional start rule.
 type Grammar struct {
 	Rules map[string]*Rule
 	Start string
+
+	order   []string          // rule names in definition order, when known
+	actions map[string]Action // semantic actions by rule name
+	skip    node              // skipper of the @skip directive, nil without one
+	scanner *scanner          // token stage of @token definitions, nil without any
 }
 
 // LoadGrammar reads a BNF grammar from an io.Reader and builds a Grammar object.
+// Imports are resolved relative to the working directory.
 func LoadGrammar(r io.Reader) (*Grammar, error) {
 	p, err := NewParser(r)
 	if err != nil {
@@ -29,18 +38,46 @@ func LoadGrammar(r io.Reader) (*Grammar, error) {
 	if err != nil {
 		return nil, err
 	}
+	ast, _, err = (&loader{}).resolve(ast, "<input>", ".")
+	if err != nil {
+		return nil, err
+	}
 	return BuildGrammar(ast)
 }
 
 // LoadGrammarFile reads a BNF grammar from a file and builds a Grammar object.
+// Imports are resolved relative to the directory of the file.
 func LoadGrammarFile(path string) (*Grammar, error) {
-	f, err := os.Open(path)
+	ast, err := LoadGrammarAST(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return BuildGrammar(ast)
+}
 
-	return LoadGrammar(f)
+// LoadGrammarFS reads a BNF grammar from a file of fsys, like an embed.FS, and
+// builds a Grammar object. Imports are resolved through fsys as well.
+func LoadGrammarFS(fsys fs.FS, path string) (*Grammar, error) {
+	ast, _, err := (&loader{fsys: fsys}).load(path)
+	if err != nil {
+		return nil, err
+	}
+	return BuildGrammar(ast)
+}
+
+// MustLoadGrammar is like LoadGrammarFS but panics when the grammar cannot be
+// loaded. It simplifies initialization of package-level grammars:
+//
+//	//go:embed grammars
+//	var grammars embed.FS
+//
+//	var expr = bnf.MustLoadGrammar(grammars, "grammars/expr.bnf")
+func MustLoadGrammar(fsys fs.FS, path string) *Grammar {
+	g, err := LoadGrammarFS(fsys, path)
+	if err != nil {
+		panic(fmt.Sprintf("bnf: loading grammar %s: %v", path, err))
+	}
+	return g
 }
 
 // LoadGrammarString reads a BNF grammar from a string and builds a Grammar object.
@@ -48,6 +85,19 @@ func LoadGrammarString(s string) (*Grammar, error) {
 	return LoadGrammar(strings.NewReader(s))
 }
 
+// ruleNames returns the rule names in definition order, or sorted when the order is unknown.
+func (g *Grammar) ruleNames() []string {
+	if len(g.order) == len(g.Rules) {
+		return g.order
+	}
+	names := make([]string, 0, len(g.Rules))
+	for name := range g.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Resolve recursively links all non-terminal nodes in the grammar to their rule definitions.
 func (g *Grammar) Resolve() error {
 	for _, rule := range g.Rules {
@@ -55,10 +105,21 @@ func (g *Grammar) Resolve() error {
 			return err
 		}
 	}
-
+	if g.skip != nil {
+		return resolveNode(g.skip, g.Rules)
+	}
 	return nil
 }
 
+// top returns the node matching the whole input from rule: its expression,
+// with the text of @skip allowed before and after it.
+func (g *Grammar) top(rule *Rule) node {
+	if g.skip == nil {
+		return rule.Expr
+	}
+	return &sequence{Elements: []node{g.skip, rule.Expr, g.skip}}
+}
+
 func resolveNode(n node, rules map[string]*Rule) error {
 	switch t := n.(type) {
 	case *nonTerminal:
@@ -83,6 +144,19 @@ func resolveNode(n node, rules map[string]*Rule) error {
 		return resolveNode(t.Node, rules)
 	case *optional:
 		return resolveNode(t.Node, rules)
+	case *notPredicate:
+		return resolveNode(t.Node, rules)
+	case *skipper:
+		return resolveNode(t.Node, rules)
+	case *dropped:
+		return resolveNode(t.Node, rules)
+	case *token:
+		return resolveNode(t.def.node, rules)
+	case *difference:
+		if err := resolveNode(t.Node, rules); err != nil {
+			return err
+		}
+		return resolveNode(t.Except, rules)
 	}
 	return nil
 }
@@ -125,7 +199,7 @@ func (g *Grammar) MatchFrom(start string, input string) (bool, error) {
 	}
 
 	ctx := NewContext(input)
-	matches, err := ctx.Match(rule.Expr, 0)
+	matches, err := ctx.Match(g.top(rule), 0)
 	if err != nil {
 		return false, err
 	}
@@ -143,7 +217,7 @@ func (g *Grammar) MatchFrom(start string, input string) (bool, error) {
 func (g *Grammar) MatchPrefix(input string) bool {
 	start := g.Rules[g.Start]
 	ctx := NewContext(input)
-	matches, err := ctx.Match(start.Expr, 0)
+	matches, err := ctx.Match(g.top(start), 0)
 	if err != nil {
 		return false
 	}
@@ -160,14 +234,17 @@ func (g *Grammar) Parse(input string) (*ASTNode, error) {
 		return nil, fmt.Errorf("unknown start rule: %s", g.Start)
 	}
 
-	ctx := NewContext(input)
-	matches, err := ctx.Match(rule.Expr, 0)
+	return g.parse(NewContext(input), rule)
+}
+
+func (g *Grammar) parse(ctx *context, rule *Rule) (*ASTNode, error) {
+	matches, err := ctx.Match(g.top(rule), 0)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, m := range matches {
-		if m.End == len(input) {
+		if m.End == len(ctx.input) {
 			// Success!
 			// If result has multiple nodes (e.g. sequence at top level), wrap them.
 			// But since we matched a Rule (which is a NonTerminal implied or explicit?),
@@ -185,6 +262,7 @@ func (g *Grammar) Parse(input string) (*ASTNode, error) {
 			return &ASTNode{
 				Type:     g.Start,
 				Children: m.Nodes,
+				End:      m.End,
 			}, nil
 		}
 	}
@@ -0,0 +1,219 @@
+package bnf
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// loader reads grammar files and resolves their @import directives.
+type loader struct {
+	fsys  fs.FS    // file system to read from, the OS one when nil
+	stack []string // files being loaded, to detect import cycles
+}
+
+// LoadGrammarAST parses a grammar file and merges the rules of its imports
+// into the returned AST, without building it.
+func LoadGrammarAST(path string) (*GrammarAST, error) {
+	l := &loader{}
+	ast, _, err := l.load(path)
+	return ast, err
+}
+
+// load parses the file at name and appends the rules of its imports after
+// its own ones, so the start rule stays the first rule of the file. It also
+// returns the file defining each rule.
+func (l *loader) load(name string) (*GrammarAST, map[string]string, error) {
+	if i := slices.Index(l.stack, name); i >= 0 {
+		cycle := append(slices.Clone(l.stack[i:]), name)
+		return nil, nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+	}
+	src, err := l.readFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := NewParser(bytes.NewReader(src))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	ast, err := p.ParseGrammar()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	l.stack = append(l.stack, name)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	return l.resolve(ast, name, l.dir(name))
+}
+
+// resolve merges the imports of ast, read from the file name, resolving them
+// relative to dir. A rule reached through several imports of the same file is
+// kept once, the same name defined by different files is an error.
+func (l *loader) resolve(ast *GrammarAST, name, dir string) (*GrammarAST, map[string]string, error) {
+	origin := map[string]string{}
+	for _, r := range ast.Rules {
+		origin[r.Name] = name
+	}
+	if len(ast.Imports) == 0 {
+		return ast, origin, nil
+	}
+
+	merged := &GrammarAST{Rules: slices.Clone(ast.Rules), Skip: ast.Skip, Comments: ast.Comments}
+	for _, imp := range ast.Imports {
+		var sub *GrammarAST
+		var subOrigin map[string]string
+		if imp.Path == stdImport {
+			sub, subOrigin = stdGrammar(), map[string]string{}
+			for _, r := range sub.Rules {
+				subOrigin[r.Name] = stdImport
+			}
+		} else {
+			var err error
+			sub, subOrigin, err = l.load(l.join(dir, imp.Path))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if imp.Alias != "" {
+			sub, subOrigin = namespace(sub, subOrigin, imp.Alias)
+		}
+		for _, r := range sub.Rules {
+			file := subOrigin[r.Name]
+			if prev, ok := origin[r.Name]; ok {
+				if prev == file {
+					continue // the same file imported twice
+				}
+				return nil, nil, fmt.Errorf("rule %s defined in both %s and %s", r.Name, prev, file)
+			}
+			origin[r.Name] = file
+			merged.Rules = append(merged.Rules, r)
+		}
+	}
+
+	// keep only the rules of the standard library the grammar uses
+	used := map[string]bool{}
+	var exprs []ExprAST
+	for _, r := range merged.Rules {
+		exprs = append(exprs, r.Expr)
+	}
+	if merged.Skip != nil {
+		exprs = append(exprs, merged.Skip.Expr)
+	}
+	for _, e := range exprs {
+		mapIdents(e, func(name string) string {
+			used[name] = true
+			return name
+		})
+	}
+	merged.Rules = slices.DeleteFunc(merged.Rules, func(r *RuleAST) bool {
+		return origin[r.Name] == stdImport && !used[r.Name]
+	})
+	return merged, origin, nil
+}
+
+func (l *loader) readFile(name string) ([]byte, error) {
+	if l.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(l.fsys, name)
+}
+
+func (l *loader) dir(name string) string {
+	if l.fsys == nil {
+		return filepath.Dir(name)
+	}
+	return path.Dir(name)
+}
+
+func (l *loader) join(dir, name string) string {
+	if l.fsys == nil {
+		if filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+	return path.Join(dir, name)
+}
+
+// namespace prefixes the rules defined by ast, and the references to them,
+// with alias, so the rules of common.bnf imported as common become common.name.
+func namespace(ast *GrammarAST, origin map[string]string, alias string) (*GrammarAST, map[string]string) {
+	defined := map[string]bool{}
+	for _, r := range ast.Rules {
+		defined[r.Name] = true
+	}
+	rename := func(name string) string {
+		if defined[name] {
+			return alias + "." + name
+		}
+		return name
+	}
+	out := &GrammarAST{}
+	outOrigin := map[string]string{}
+	for _, r := range ast.Rules {
+		c := *r
+		c.Name = rename(r.Name)
+		c.Expr = mapIdents(r.Expr, func(name string) string {
+			if slices.Contains(r.Params, name) {
+				return name
+			}
+			return rename(name)
+		})
+		out.Rules = append(out.Rules, &c)
+		outOrigin[c.Name] = origin[r.Name]
+	}
+	return out, outOrigin
+}
+
+// mapIdents returns a copy of e with rule references renamed by f.
+func mapIdents(e ExprAST, f func(string) string) ExprAST {
+	return mapExpr(e, func(e ExprAST) ExprAST {
+		switch t := e.(type) {
+		case *IdentAST:
+			return &IdentAST{Name: f(t.Name)}
+		case *CallAST:
+			return &CallAST{Name: f(t.Name), Args: t.Args}
+		}
+		return e
+	})
+}
+
+// mapExpr returns a copy of e with its nodes replaced by f, bottom-up.
+func mapExpr(e ExprAST, f func(ExprAST) ExprAST) ExprAST {
+	switch t := e.(type) {
+	case *SeqAST:
+		elems := make([]ExprAST, len(t.Elements))
+		for i, e := range t.Elements {
+			elems[i] = mapExpr(e, f)
+		}
+		return f(&SeqAST{Elements: elems})
+	case *ChoiceAST:
+		opts := make([]ExprAST, len(t.Options))
+		for i, o := range t.Options {
+			opts[i] = mapExpr(o, f)
+		}
+		return f(&ChoiceAST{Options: opts})
+	case *RepeatAST:
+		return f(&RepeatAST{Node: mapExpr(t.Node, f), Min: t.Min, Max: t.Max})
+	case *NotAST:
+		return f(&NotAST{Node: mapExpr(t.Node, f)})
+	case *DropAST:
+		return f(&DropAST{Node: mapExpr(t.Node, f)})
+	case *DiffAST:
+		return f(&DiffAST{Node: mapExpr(t.Node, f), Except: mapExpr(t.Except, f)})
+	case *SepListAST:
+		return f(&SepListAST{Node: mapExpr(t.Node, f), Sep: mapExpr(t.Sep, f), Min: t.Min, Trailing: t.Trailing})
+	case *CallAST:
+		args := make([]ExprAST, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = mapExpr(a, f)
+		}
+		return f(&CallAST{Name: t.Name, Args: args})
+	}
+	return f(e)
+}
@@ -0,0 +1,150 @@
+package bnf_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeGrammars writes the grammar files to a temporary directory and returns it.
+func writeGrammars(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	dir := writeGrammars(t, map[string]string{
+		"main.bnf":       "@import \"lib/common.bnf\"\n<list> ::= <digit> (\",\" <digit>)*\n",
+		"lib/common.bnf": "<digit> ::= \"0\" | \"1\" | <more>\n<more> ::= \"2\"\n",
+	})
+	g, err := bnf.LoadGrammarFile(filepath.Join(dir, "main.bnf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "list", g.Start)
+	for _, in := range []string{"0", "1,2,0"} {
+		ok, err := g.Match(in)
+		assert.True(t, ok, in)
+		assert.NoError(t, err)
+	}
+}
+
+func TestImport_Namespace(t *testing.T) {
+	t.Parallel()
+
+	dir := writeGrammars(t, map[string]string{
+		"main.bnf":   "@import common \"common.bnf\"\nnumber ::= common.digit+ | digit\ndigit ::= \"x\"\n",
+		"common.bnf": "digit ::= \"0\" | \"1\" | more\nmore ::= \"2\"\n",
+	})
+	g, err := bnf.LoadGrammarFile(filepath.Join(dir, "main.bnf"))
+	assert.NoError(t, err)
+	assert.Contains(t, g.Rules, "common.digit")
+	assert.Contains(t, g.Rules, "common.more")
+	assert.NotContains(t, g.Rules, "more")
+
+	ok, err := g.Match("012")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, _ = g.Match("x")
+	assert.True(t, ok)
+
+	tree, err := g.Parse("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "common.digit", tree.Children[0].Type)
+}
+
+func TestImport_Diamond(t *testing.T) {
+	t.Parallel()
+
+	dir := writeGrammars(t, map[string]string{
+		"main.bnf": "@import \"a.bnf\"\n@import \"b.bnf\"\ns ::= a b\n",
+		"a.bnf":    "@import \"d.bnf\"\na ::= d\n",
+		"b.bnf":    "@import \"d.bnf\"\nb ::= d d\n",
+		"d.bnf":    "d ::= \"d\"\n",
+	})
+	g, err := bnf.LoadGrammarFile(filepath.Join(dir, "main.bnf"))
+	assert.NoError(t, err)
+	ok, err := g.Match("ddd")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestImport_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := writeGrammars(t, map[string]string{
+		"cycle.bnf":    "@import \"a.bnf\"\ns ::= a\n",
+		"a.bnf":        "@import \"b.bnf\"\na ::= b\n",
+		"b.bnf":        "@import \"a.bnf\"\nb ::= \"b\"\n",
+		"conflict.bnf": "@import \"d.bnf\"\ns ::= d\nd ::= \"x\"\n",
+		"d.bnf":        "d ::= \"d\"\n",
+		"missing.bnf":  "@import \"none.bnf\"\ns ::= \"s\"\n",
+		"bad.bnf":      "@include \"d.bnf\"\ns ::= \"s\"\n",
+	})
+	tests := map[string]string{
+		"cycle.bnf":    "import cycle: " + filepath.Join(dir, "a.bnf") + " -> " + filepath.Join(dir, "b.bnf") + " -> " + filepath.Join(dir, "a.bnf"),
+		"conflict.bnf": "rule d defined in both " + filepath.Join(dir, "conflict.bnf") + " and " + filepath.Join(dir, "d.bnf"),
+		"missing.bnf":  "none.bnf",
+		"bad.bnf":      "unknown directive: @include",
+	}
+	for file, msg := range tests {
+		_, err := bnf.LoadGrammarFile(filepath.Join(dir, file))
+		if assert.Error(t, err, file) {
+			assert.Contains(t, err.Error(), msg, file)
+		}
+	}
+}
+
+func TestFormat_Imports(t *testing.T) {
+	t.Parallel()
+
+	out, err := bnf.Format([]byte("# shared rules\n@import   \"common.bnf\"\n@import c 'c.bnf'\n\nx ::= c.digit | y\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# shared rules\n@import \"common.bnf\"\n@import c \"c.bnf\"\n\nx ::= c.digit | y\n", string(out))
+}
+
+func TestLoadGrammarFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"grammars/main.bnf":   {Data: []byte("@import c \"../shared/common.bnf\"\nlist ::= c.digit (\",\" c.digit)*\n")},
+		"shared/common.bnf":   {Data: []byte("@import \"lexical.bnf\"\ndigit ::= zero | one\n")},
+		"shared/lexical.bnf":  {Data: []byte("zero ::= \"0\"\none ::= \"1\"\n")},
+		"grammars/cycle.bnf":  {Data: []byte("@import \"cycle.bnf\"\ns ::= \"s\"\n")},
+		"grammars/escape.bnf": {Data: []byte("@import \"../../outside.bnf\"\ns ::= \"s\"\n")},
+	}
+	g, err := bnf.LoadGrammarFS(fsys, "grammars/main.bnf")
+	assert.NoError(t, err)
+	assert.Contains(t, g.Rules, "c.zero")
+	ok, err := g.Match("0,1,1")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	_, err = bnf.LoadGrammarFS(fsys, "grammars/cycle.bnf")
+	assert.EqualError(t, err, "import cycle: grammars/cycle.bnf -> grammars/cycle.bnf")
+	_, err = bnf.LoadGrammarFS(fsys, "grammars/escape.bnf")
+	assert.Error(t, err)
+	_, err = bnf.LoadGrammarFS(fsys, "grammars/missing.bnf")
+	assert.Error(t, err)
+}
+
+func TestMustLoadGrammar(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{"bad.bnf": {Data: []byte("s ::= (")}}
+	assert.PanicsWithValue(t, "bnf: loading grammar missing.bnf: open missing.bnf: file does not exist", func() {
+		bnf.MustLoadGrammar(fsys, "missing.bnf")
+	})
+	assert.Panics(t, func() { bnf.MustLoadGrammar(fsys, "bad.bnf") })
+}
@@ -3,6 +3,8 @@ package bnf
 type MatchResult struct {
 	End   int
 	Nodes []*ASTNode
+
+	hits *trace // derivation trace, only recorded when the context traces coverage
 }
 
 type node interface {
@@ -4,50 +4,112 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TokenType represents the category of a lexed token.
 type TokenType int
 
 const (
-	EOF      TokenType = iota
-	IDENT              // generic identifier or BNF rule name
-	NT_IDENT           // BNF rule name explicitly in angle brackets (<rule>)
-	STRING             // quoted string literal
-	REGEX              // regex pattern enclosed in /.../
-	ASSIGN             // the ::= operator
-	PIPE               // the | operator
-	STAR               // the * operator
-	PLUS               // the + operator
-	QMARK              // the ? operator
-	LPAREN             // the ( operator
-	RPAREN             // the ) operator
+	EOF       TokenType = iota
+	IDENT               // generic identifier or BNF rule name
+	NT_IDENT            // BNF rule name explicitly in angle brackets (<rule>)
+	STRING              // quoted string literal
+	ISTRING             // case-insensitive string literal "..."i
+	REGEX               // regex pattern enclosed in /.../
+	ASSIGN              // the ::= operator
+	PIPE                // the | operator
+	STAR                // the * operator
+	PLUS                // the + operator
+	QMARK               // the ? operator
+	LPAREN              // the ( operator
+	RPAREN              // the ) operator
+	DIRECTIVE           // a directive like @import, Text holds its name
+	CLASS               // character class [...] or \p{Name}
+	DOTDOT              // the .. operator of character ranges
+	DOT                 // the . wildcard matching any character
+	DOLLAR              // the $ anchor matching the end of input
+	BANG                // the ! negative lookahead operator
+	MINUS               // the - difference operator
+	NT_CALL             // opening of a parameterized rule <name followed by parameters or arguments
+	RANGLE              // the > closing a parameterized rule
+	PERCENT             // a separated list operator: %, %*, %% or %%*
+	DROP                // the - prefix leaving its operand out of the parse tree
 )
 
 // Token represents a single atom (lexeme) in the input BNF grammar.
 type Token struct {
 	Type TokenType
 	Text string
+	Line int // line the token starts on, counted from 1
+}
+
+// Comment is a line comment of the grammar source, retained for printing.
+type Comment struct {
+	Text   string // comment including its marker (#, ; or //), without the line break
+	Line   int    // line of the comment, counted from 1
+	Inline bool   // the comment follows a token on the same line
 }
 
 // Lexer breaks the BNF grammar input into a stream of tokens.
 type Lexer struct {
 	r *bufio.Reader
+
+	line      int       // current line
+	last      rune      // last rune read, for unreading
+	tokenLine int       // line of the last token returned
+	prev      TokenType // type of the last token returned
+	comments  []Comment
 }
 
 // NewLexer creates a new Lexer for the given reader.
 func NewLexer(r io.Reader) *Lexer {
-	return &Lexer{r: bufio.NewReader(r)}
+	return &Lexer{r: bufio.NewReader(r), line: 1}
+}
+
+// Comments returns the comments skipped so far, in source order.
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
+
+func (l *Lexer) readRune() (rune, int, error) {
+	ch, size, err := l.r.ReadRune()
+	if err == nil {
+		l.last = ch
+		if ch == '\n' {
+			l.line++
+		}
+	}
+	return ch, size, err
+}
+
+func (l *Lexer) unreadRune() error {
+	if l.last == '\n' {
+		l.line--
+	}
+	return l.r.UnreadRune()
 }
 
 // Next returns the next token from the input stream.
 func (l *Lexer) Next() (Token, error) {
+	tok, err := l.next()
+	if err == nil {
+		tok.Line = l.tokenLine
+		l.prev = tok.Type
+	}
+	return tok, err
+}
+
+func (l *Lexer) next() (Token, error) {
 	// 1. skip whitespace and comments
 	var ch rune
 	var err error
+	spaced := false // whitespace or a comment precedes the token
 	for {
-		ch, _, err = l.r.ReadRune()
+		ch, _, err = l.readRune()
 		if err == io.EOF {
 			return Token{Type: EOF}, nil
 		}
@@ -56,17 +118,19 @@ func (l *Lexer) Next() (Token, error) {
 		}
 
 		if isWhitespace(ch) {
+			spaced = true
 			continue
 		}
 
 		// line comment
 		if ch == ';' || ch == '#' {
-			if err := l.skipUntilEOL(); err != nil {
+			if err := l.skipComment(string(ch)); err != nil {
 				if err == io.EOF {
 					return Token{Type: EOF}, nil
 				}
 				return Token{}, err
 			}
+			spaced = true
 			continue
 		}
 
@@ -74,13 +138,14 @@ func (l *Lexer) Next() (Token, error) {
 			// Peek at the next byte to see if it's another '/'
 			peek, err := l.r.Peek(1)
 			if err == nil && len(peek) > 0 && peek[0] == '/' {
-				l.r.ReadRune() // consume the second '/'
-				if err := l.skipUntilEOL(); err != nil {
+				l.readRune() // consume the second '/'
+				if err := l.skipComment("//"); err != nil {
 					if err == io.EOF {
 						return Token{Type: EOF}, nil
 					}
 					return Token{}, err
 				}
+				spaced = true
 				continue
 			}
 			// Not a comment, so the / is the start of a token (likely a regex)
@@ -91,6 +156,7 @@ func (l *Lexer) Next() (Token, error) {
 		// ch is meaningful, so we break the loop to process it in the main switch
 		break
 	}
+	l.tokenLine = l.line
 
 	// 2. identificator
 	if isIdentStart(ch) {
@@ -98,7 +164,13 @@ func (l *Lexer) Next() (Token, error) {
 		sb.WriteRune(ch)
 
 		for {
-			ch, _, err := l.r.ReadRune()
+			if l.peekQualifier() {
+				// qualified name of an imported rule, like common.digit
+				l.readRune()
+				sb.WriteRune('.')
+				continue
+			}
+			ch, _, err := l.readRune()
 			if err != nil {
 				if err == io.EOF {
 					break // legitimate end of ident
@@ -106,7 +178,7 @@ func (l *Lexer) Next() (Token, error) {
 				return Token{}, err
 			}
 			if !isIdentPart(ch) {
-				l.r.UnreadRune()
+				l.unreadRune()
 				break
 			}
 			sb.WriteRune(ch)
@@ -123,7 +195,7 @@ func (l *Lexer) Next() (Token, error) {
 		var sb strings.Builder
 
 		for {
-			ch, _, err := l.r.ReadRune()
+			ch, _, err := l.readRune()
 			if err != nil {
 				if err == io.EOF {
 					return Token{}, fmt.Errorf("unterminated <identifier>")
@@ -133,7 +205,11 @@ func (l *Lexer) Next() (Token, error) {
 			if ch == '>' {
 				break
 			}
-			if !isIdentPart(ch) {
+			if unicode.IsSpace(ch) && sb.Len() > 0 {
+				// <name X Y> of a parameterized rule, the rest are ordinary tokens
+				return Token{Type: NT_CALL, Text: sb.String()}, nil
+			}
+			if !isIdentPart(ch) && ch != '.' {
 				return Token{}, fmt.Errorf("invalid character in <identifier>: %q", ch)
 			}
 			sb.WriteRune(ch)
@@ -144,7 +220,7 @@ func (l *Lexer) Next() (Token, error) {
 		}
 
 		return Token{
-			Type: IDENT, // normalize NT_IDENT as <> are just a sugar here
+			Type: NT_IDENT, // the parser treats it as IDENT, <> are just a sugar kept for printing
 			Text: sb.String(),
 		}, nil
 	}
@@ -154,7 +230,7 @@ func (l *Lexer) Next() (Token, error) {
 		var sb strings.Builder
 
 		for {
-			ch, _, err := l.r.ReadRune()
+			ch, _, err := l.readRune()
 			if err != nil {
 				if err == io.EOF {
 					return Token{}, fmt.Errorf("unterminated regex literal")
@@ -168,7 +244,7 @@ func (l *Lexer) Next() (Token, error) {
 
 			// Handle escape sequences in regex
 			if ch == '\\' {
-				esc, _, err := l.r.ReadRune()
+				esc, _, err := l.readRune()
 				if err != nil {
 					if err == io.EOF {
 						return Token{}, fmt.Errorf("unterminated escape sequence in regex")
@@ -196,7 +272,7 @@ func (l *Lexer) Next() (Token, error) {
 		var sb strings.Builder
 
 		for {
-			ch, _, err := l.r.ReadRune()
+			ch, _, err := l.readRune()
 			if err != nil {
 				if err == io.EOF {
 					return Token{}, fmt.Errorf("unterminated string literal")
@@ -209,26 +285,18 @@ func (l *Lexer) Next() (Token, error) {
 			}
 
 			if ch == '\\' {
-				esc, _, err := l.r.ReadRune()
+				seq, err := l.readEscape()
 				if err != nil {
-					if err == io.EOF {
-						return Token{}, fmt.Errorf("unterminated escape sequence")
-					}
 					return Token{}, err
 				}
-				switch esc {
-				case '"':
-					sb.WriteRune('"')
-				case '\'':
-					sb.WriteRune('\'')
-				case '\\':
-					sb.WriteRune('\\')
-				case 'n':
-					sb.WriteRune('\n')
-				case 't':
-					sb.WriteRune('\t')
-				default:
-					return Token{}, fmt.Errorf("unknown escape sequence: \\%c", esc)
+				r, isByte, err := unescape(seq)
+				if err != nil {
+					return Token{}, err
+				}
+				if isByte {
+					sb.WriteByte(byte(r))
+				} else {
+					sb.WriteRune(r)
 				}
 				continue
 			}
@@ -236,17 +304,126 @@ func (l *Lexer) Next() (Token, error) {
 			sb.WriteRune(ch)
 		}
 
+		// "..."i suffix
+		if b, _ := l.r.Peek(2); len(b) > 0 && b[0] == 'i' && (len(b) == 1 || !isIdentPart(rune(b[1]))) {
+			l.readRune()
+			return Token{Type: ISTRING, Text: sb.String()}, nil
+		}
 		return Token{
 			Type: STRING,
 			Text: sb.String(),
 		}, nil
 	}
 
-	// 5. ASSIGN ::=
+	// 4.5. character class [...] or \p{Name}
+	if ch == '[' {
+		var sb strings.Builder
+		sb.WriteRune(ch)
+		for {
+			ch, _, err := l.readRune()
+			if err != nil || ch == '\n' {
+				return Token{}, fmt.Errorf("unterminated character class")
+			}
+			sb.WriteRune(ch)
+			if ch == ']' {
+				break
+			}
+			if ch == '\\' {
+				esc, _, err := l.readRune()
+				if err != nil || esc == '\n' {
+					return Token{}, fmt.Errorf("unterminated character class")
+				}
+				sb.WriteRune(esc)
+			}
+		}
+		return Token{Type: CLASS, Text: sb.String()}, nil
+	}
+	if ch == '\\' {
+		p, _, err := l.readRune()
+		if err != nil || p != 'p' && p != 'P' {
+			return Token{}, fmt.Errorf("expected \\p{...} or \\P{...}")
+		}
+		if b, _, err := l.readRune(); err != nil || b != '{' {
+			return Token{}, fmt.Errorf("expected { after \\%c", p)
+		}
+		var sb strings.Builder
+		for {
+			ch, _, err := l.readRune()
+			if err != nil || !isIdentPart(ch) && ch != '}' {
+				return Token{}, fmt.Errorf("unterminated \\%c{", p)
+			}
+			if ch == '}' {
+				break
+			}
+			sb.WriteRune(ch)
+		}
+		return Token{Type: CLASS, Text: `\` + string(p) + "{" + sb.String() + "}"}, nil
+	}
+
+	// 4.6. code points %x41, %x0D.0A or range %x41-5A
+	if ch == '%' {
+		// anything else is a separated list, like item %sep
+		if b, _ := l.r.Peek(2); len(b) < 2 || b[0] != 'x' || !isHexDigit(rune(b[1])) {
+			return l.readSepOp()
+		}
+		l.readRune()
+		lo, err := l.readCodePoint()
+		if err != nil {
+			return Token{}, err
+		}
+		if b, _ := l.r.Peek(1); len(b) == 1 && b[0] == '-' {
+			l.readRune()
+			hi, err := l.readCodePoint()
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Type: CLASS, Text: fmt.Sprintf(`[\U%08X-\U%08X]`, lo, hi)}, nil
+		}
+		s := string(lo)
+		for {
+			if b, _ := l.r.Peek(2); len(b) < 2 || b[0] != '.' || !isHexDigit(rune(b[1])) {
+				break
+			}
+			l.readRune()
+			r, err := l.readCodePoint()
+			if err != nil {
+				return Token{}, err
+			}
+			s += string(r)
+		}
+		return Token{Type: STRING, Text: s}, nil
+	}
+
+	// 5. directive @name
+	if ch == '@' {
+		ch, _, err := l.readRune()
+		if err != nil || !isIdentStart(ch) {
+			return Token{}, fmt.Errorf("expected a directive name after @")
+		}
+		var sb strings.Builder
+		sb.WriteRune(ch)
+		for {
+			ch, _, err := l.readRune()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				return Token{}, err
+			}
+			if !isIdentPart(ch) {
+				l.unreadRune()
+				break
+			}
+			sb.WriteRune(ch)
+		}
+		return Token{Type: DIRECTIVE, Text: sb.String()}, nil
+	}
+
+	// 6. ASSIGN ::=
 	if ch == ':' {
-		ch2, _, err := l.r.ReadRune()
+		ch2, _, err := l.readRune()
 		if err == nil && ch2 == ':' {
-			ch3, _, err := l.r.ReadRune()
+			ch3, _, err := l.readRune()
 			if err == nil && ch3 == '=' {
 				return Token{Type: ASSIGN, Text: "::="}, nil
 			}
@@ -254,7 +431,7 @@ func (l *Lexer) Next() (Token, error) {
 		return Token{}, fmt.Errorf("expected ::=")
 	}
 
-	// 6. Single symbols
+	// 7. Single symbols
 	switch ch {
 	case '|':
 		return Token{Type: PIPE, Text: "|"}, nil
@@ -268,11 +445,153 @@ func (l *Lexer) Next() (Token, error) {
 		return Token{Type: LPAREN, Text: "("}, nil
 	case ')':
 		return Token{Type: RPAREN, Text: ")"}, nil
+	case '.':
+		next, _, err := l.readRune()
+		if err == nil && next == '.' {
+			return Token{Type: DOTDOT, Text: ".."}, nil
+		}
+		if err == nil {
+			l.unreadRune()
+		}
+		return Token{Type: DOT, Text: "."}, nil
+	case '$':
+		return Token{Type: DOLLAR, Text: "$"}, nil
+	case '!':
+		return Token{Type: BANG, Text: "!"}, nil
+	case '-':
+		// where no operand can end, like in <a> ::= -":" <b>, - drops its
+		// operand from the tree; after an operand it is the difference <a> - <b>
+		switch l.prev {
+		case EOF, ASSIGN, PIPE, LPAREN, BANG, DROP, MINUS, PERCENT:
+			return Token{Type: DROP, Text: "-"}, nil
+		}
+		// spaced like a drop but after an operand, as in <a> -":" <b>
+		if peek, err := l.r.Peek(1); spaced && err == nil && !isWhitespace(rune(peek[0])) {
+			return Token{}, fmt.Errorf(`ambiguous "-" after an operand: write a - b for a difference or a (-b) to leave b out of the parse tree`)
+		}
+		return Token{Type: MINUS, Text: "-"}, nil
+	case '>':
+		return Token{Type: RANGLE, Text: ">"}, nil
 	}
 
 	return Token{}, fmt.Errorf("unexpected character: %q", ch)
 }
 
+// readEscape reads an escape sequence following a backslash: the escaped
+// character and the digits of numeric escapes like \x41.
+func (l *Lexer) readEscape() (string, error) {
+	esc, _, err := l.readRune()
+	if err != nil {
+		if err == io.EOF {
+			return "", fmt.Errorf("unterminated escape sequence")
+		}
+		return "", err
+	}
+	seq := string(esc)
+	digit := escapeDigit(esc)
+	for range escapeLen(esc) {
+		ch, _, err := l.readRune()
+		if err != nil {
+			break
+		}
+		if !digit(ch) {
+			l.unreadRune()
+			break
+		}
+		seq += string(ch)
+	}
+	return seq, nil
+}
+
+// readCodePoint reads the hexadecimal digits of a code point.
+func (l *Lexer) readCodePoint() (rune, error) {
+	var digits strings.Builder
+	for {
+		ch, _, err := l.readRune()
+		if err != nil {
+			break
+		}
+		if !isHexDigit(ch) {
+			l.unreadRune()
+			break
+		}
+		digits.WriteRune(ch)
+	}
+	v, err := strconv.ParseUint(digits.String(), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		return 0, fmt.Errorf("invalid code point %%x%s", digits.String())
+	}
+	return rune(v), nil
+}
+
+// splitEscape splits the escape sequence at the start of s, which follows a
+// backslash, from the rest of s.
+func splitEscape(s string) (seq, rest string) {
+	esc, size := utf8.DecodeRuneInString(s)
+	digit := escapeDigit(esc)
+	n := size
+	for range escapeLen(esc) {
+		if n >= len(s) || !digit(rune(s[n])) {
+			break
+		}
+		n++
+	}
+	return s[:n], s[n:]
+}
+
+// escapeLen returns the number of digits following \esc, like the two
+// hexadecimal digits of \x41.
+func escapeLen(esc rune) int {
+	switch {
+	case esc == 'x':
+		return 2
+	case esc == 'u':
+		return 4
+	case esc == 'U':
+		return 8
+	case isOctalDigit(esc):
+		return 2
+	}
+	return 0
+}
+
+func escapeDigit(esc rune) func(rune) bool {
+	if isOctalDigit(esc) {
+		return isOctalDigit
+	}
+	return isHexDigit
+}
+
+func isOctalDigit(r rune) bool {
+	return r >= '0' && r <= '7'
+}
+
+// unescape decodes an escape sequence without its backslash as in Go string
+// literals, with \0 alone standing for the NUL character. isByte tells that
+// r is a single byte (\xff, \377) rather than a character to encode as UTF-8.
+func unescape(seq string) (r rune, isByte bool, err error) {
+	switch seq {
+	case "0":
+		return 0, false, nil
+	case `'`:
+		return '\'', false, nil
+	}
+	value, multibyte, tail, err := strconv.UnquoteChar(`\`+seq, '"')
+	if err != nil || tail != "" {
+		return 0, false, fmt.Errorf("unknown escape sequence: \\%s", seq)
+	}
+	return value, !multibyte && value >= utf8.RuneSelf, nil
+}
+
+// escapeRune returns the escape sequence of r, if r is not printable.
+func escapeRune(r rune) (string, bool) {
+	if unicode.IsPrint(r) {
+		return "", false
+	}
+	q := strconv.QuoteRune(r)
+	return q[1 : len(q)-1], true
+}
+
 func isWhitespace(ch rune) bool {
 	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
 }
@@ -287,9 +606,44 @@ func isIdentPart(ch rune) bool {
 	return isIdentStart(ch) || (ch >= '0' && ch <= '9') || ch == '-'
 }
 
-func (l *Lexer) skipUntilEOL() error {
+// skipComment records the comment starting with marker and skips it.
+func (l *Lexer) skipComment(marker string) error {
+	line := l.line
+	var sb strings.Builder
+	sb.WriteString(marker)
+	err := l.skipUntilEOL(&sb)
+	l.comments = append(l.comments, Comment{
+		Text:   strings.TrimRight(sb.String(), " \t"),
+		Line:   line,
+		Inline: l.tokenLine == line,
+	})
+	return err
+}
+
+// peekQualifier reports whether the next runes are a dot followed by the
+// start of an identifier, without consuming them: a rune read after a Peek
+// cannot be unread.
+func (l *Lexer) peekQualifier() bool {
+	b, _ := l.r.Peek(2)
+	return len(b) == 2 && b[0] == '.' && isIdentStart(rune(b[1]))
+}
+
+// readSepOp reads the rest of a separated list operator after its first %:
+// %, %* and, allowing a trailing separator, %% and %%*.
+func (l *Lexer) readSepOp() (Token, error) {
+	op := "%"
+	for _, next := range []byte{'%', '*'} {
+		if b, _ := l.r.Peek(1); len(b) == 1 && b[0] == next {
+			l.readRune()
+			op += string(next)
+		}
+	}
+	return Token{Type: PERCENT, Text: op}, nil
+}
+
+func (l *Lexer) skipUntilEOL(sb *strings.Builder) error {
 	for {
-		ch, _, err := l.r.ReadRune()
+		ch, _, err := l.readRune()
 		if err != nil {
 			return err
 		}
@@ -301,11 +655,12 @@ func (l *Lexer) skipUntilEOL() error {
 		// Windows, or old Mac style new line
 		if ch == '\r' {
 			// check if it's not \r\n
-			next, _, err := l.r.ReadRune()
+			next, _, err := l.readRune()
 			if err == nil && next != '\n' {
-				l.r.UnreadRune()
+				l.unreadRune()
 			}
 			return nil
 		}
+		sb.WriteRune(ch)
 	}
 }
@@ -76,3 +76,228 @@ func TestLexer_EmptyString(t *testing.T) {
 	assert.Equal(t, STRING, tok.Type)
 	assert.Equal(t, "b", tok.Text)
 }
+
+func TestLexer_Comments(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader("# first\na ::= \"x\" ; trailing\r\n\n// last"))
+	var lines []int
+	for {
+		tok, err := l.Next()
+		assert.NoError(t, err)
+		if tok.Type == EOF {
+			break
+		}
+		lines = append(lines, tok.Line)
+	}
+	assert.Equal(t, []int{2, 2, 2}, lines)
+	assert.Equal(t, []Comment{
+		{Text: "# first", Line: 1},
+		{Text: "; trailing", Line: 2, Inline: true},
+		{Text: "// last", Line: 4},
+	}, l.Comments())
+}
+
+func TestLexer_Directives(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader(`@import c "common.bnf" x ::= c.digit <c.alpha> c. d`))
+	var toks []Token
+	for {
+		tok, err := l.Next()
+		assert.NoError(t, err)
+		if tok.Type == EOF {
+			break
+		}
+		tok.Line = 0
+		toks = append(toks, tok)
+	}
+	assert.Equal(t, []Token{
+		{Type: DIRECTIVE, Text: "import"},
+		{Type: IDENT, Text: "c"},
+		{Type: STRING, Text: "common.bnf"},
+		{Type: IDENT, Text: "x"},
+		{Type: ASSIGN, Text: "::="},
+		{Type: IDENT, Text: "c.digit"},
+		{Type: NT_IDENT, Text: "c.alpha"},
+		{Type: IDENT, Text: "c"},
+	}, toks[:8])
+}
+
+func TestLexer_Escapes(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		`"\r\n"`:       "\r\n",
+		`'\a\b\f\v'`:   "\a\b\f\v",
+		`"\0"`:         "\x00",
+		`"\0x"`:        "\x00x",
+		`"\101\x41é"`:  "AAé",
+		`"\U0001F600"`: "😀",
+		`"\xff\xFE"`:   "\xff\xfe",
+		`"\'\"\\"`:     `'"\`,
+		`'it\'s'`:      "it's",
+		`%x41`:         "A",
+		`%x0D.0A`:      "\r\n",
+		`%x1F600`:      "😀",
+		`%x41.`:        "A",
+		`"a" %x42`:     "a",
+	}
+	for src, want := range tests {
+		tok, err := NewLexer(strings.NewReader(src)).Next()
+		if assert.NoError(t, err, src) {
+			assert.Equal(t, STRING, tok.Type, src)
+			assert.Equal(t, want, tok.Text, src)
+		}
+	}
+
+	tok, err := NewLexer(strings.NewReader(`%x41-5A`)).Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Token{Type: CLASS, Text: `[\U00000041-\U0000005A]`, Line: 1}, tok)
+
+	for _, src := range []string{`"\q"`, `"\x4"`, `"\u00e"`, `"\400"`, `"\8"`, `"\uD800"`, `%xD800`, `%x110000`} {
+		_, err := NewLexer(strings.NewReader(src)).Next()
+		assert.Error(t, err, src)
+	}
+}
+
+func TestLexer_Predicates(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader(`x ::= !"a". 'a'..'z' $ EOF`))
+	var types []TokenType
+	for {
+		tok, err := l.Next()
+		assert.NoError(t, err)
+		if tok.Type == EOF {
+			break
+		}
+		types = append(types, tok.Type)
+	}
+	assert.Equal(t, []TokenType{IDENT, ASSIGN, BANG, STRING, DOT, STRING, DOTDOT, STRING, DOLLAR, IDENT}, types)
+}
+
+func TestLexer_Calls(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader(`<list X S> ::= <sep-list <id> S>`))
+	var toks []Token
+	for {
+		tok, err := l.Next()
+		assert.NoError(t, err)
+		if tok.Type == EOF {
+			break
+		}
+		toks = append(toks, tok)
+	}
+	var types []TokenType
+	for _, tok := range toks {
+		types = append(types, tok.Type)
+	}
+	assert.Equal(t, []TokenType{NT_CALL, IDENT, IDENT, RANGLE, ASSIGN, NT_CALL, NT_IDENT, IDENT, RANGLE}, types)
+	assert.Equal(t, "sep-list", toks[5].Text)
+}
+
+func TestLexer_SepListOperators(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader(`x % "," %* y %%z %%*(y) %x41`))
+	var toks []Token
+	for {
+		tok, err := l.Next()
+		assert.NoError(t, err)
+		if tok.Type == EOF {
+			break
+		}
+		toks = append(toks, tok)
+	}
+	var texts []string
+	for _, tok := range toks {
+		if tok.Type == PERCENT {
+			texts = append(texts, tok.Text)
+		}
+	}
+	assert.Equal(t, []string{"%", "%*", "%%", "%%*"}, texts)
+	assert.Equal(t, Token{Type: STRING, Text: "A", Line: 1}, toks[len(toks)-1])
+}
+
+func TestLexer_SepListBeforeName(t *testing.T) {
+	t.Parallel()
+
+	// %x is a code point only when a hexadecimal digit follows
+	tests := map[string][]Token{
+		`item %sep`:   {{Type: IDENT, Text: "item"}, {Type: PERCENT, Text: "%"}, {Type: IDENT, Text: "sep"}},
+		`item %xsep`:  {{Type: IDENT, Text: "item"}, {Type: PERCENT, Text: "%"}, {Type: IDENT, Text: "xsep"}},
+		`item %%*sep`: {{Type: IDENT, Text: "item"}, {Type: PERCENT, Text: "%%*"}, {Type: IDENT, Text: "sep"}},
+		`item %x`:     {{Type: IDENT, Text: "item"}, {Type: PERCENT, Text: "%"}, {Type: IDENT, Text: "x"}},
+		`item %y41`:   {{Type: IDENT, Text: "item"}, {Type: PERCENT, Text: "%"}, {Type: IDENT, Text: "y41"}},
+		`item %x2C`:   {{Type: IDENT, Text: "item"}, {Type: STRING, Text: ","}},
+	}
+	for src, want := range tests {
+		l := NewLexer(strings.NewReader(src))
+		var toks []Token
+		for {
+			tok, err := l.Next()
+			if !assert.NoError(t, err, src) || tok.Type == EOF {
+				break
+			}
+			tok.Line = 0
+			toks = append(toks, tok)
+		}
+		assert.Equal(t, want, toks, src)
+	}
+}
+
+func TestLexer_Drop(t *testing.T) {
+	t.Parallel()
+
+	l := NewLexer(strings.NewReader(`a ::= -b c (-",") (-d|-e) f - g "h"-i j-k - -l !-m [a-z]+ - "if"`))
+	var types []TokenType
+	for {
+		tok, err := l.Next()
+		assert.NoError(t, err)
+		if tok.Type == EOF {
+			break
+		}
+		if tok.Type == DROP || tok.Type == MINUS {
+			types = append(types, tok.Type)
+		}
+	}
+	// after an operand - is always a difference
+	assert.Equal(t, []TokenType{DROP, DROP, DROP, DROP, MINUS, MINUS, MINUS, DROP, DROP, MINUS}, types)
+
+	// spaced like a drop after an operand, either could be meant
+	for _, src := range []string{`a ::= b -c`, `a ::= [a-z]+ -"if"`, "a ::= b //x\n-c"} {
+		l := NewLexer(strings.NewReader(src))
+		var err error
+		for tok := (Token{Type: IDENT}); err == nil && tok.Type != EOF; {
+			tok, err = l.Next()
+		}
+		assert.EqualError(t, err, `ambiguous "-" after an operand: write a - b for a difference or a (-b) to leave b out of the parse tree`, src)
+	}
+}
+
+func TestLexer_DotAfterIdent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]Token{
+		`x.`:    {{Type: IDENT, Text: "x"}, {Type: DOT, Text: "."}},
+		`x .`:   {{Type: IDENT, Text: "x"}, {Type: DOT, Text: "."}},
+		`x. y`:  {{Type: IDENT, Text: "x"}, {Type: DOT, Text: "."}, {Type: IDENT, Text: "y"}},
+		`c.d .`: {{Type: IDENT, Text: "c.d"}, {Type: DOT, Text: "."}},
+	}
+	for src, want := range tests {
+		l := NewLexer(strings.NewReader(src))
+		var toks []Token
+		for {
+			tok, err := l.Next()
+			assert.NoError(t, err, src)
+			if err != nil || tok.Type == EOF {
+				break
+			}
+			tok.Line = 0
+			toks = append(toks, tok)
+		}
+		assert.Equal(t, want, toks, src)
+	}
+}
@@ -0,0 +1,105 @@
+package bnf
+
+import (
+	"math/rand/v2"
+)
+
+// edit replaces input[pos:end] with text.
+type edit struct {
+	pos, end int
+	text     string
+}
+
+// Mutate derives up to n distinct near-miss inputs from a valid one: it parses
+// the input and applies single grammar-aware mutations to the parse tree, such
+// as dropping an element, swapping a terminal for another terminal of the
+// grammar or duplicating an element. Only mutants rejected by Match are kept.
+func (g *Grammar) Mutate(r *rand.Rand, valid string, n int) ([]string, error) {
+	tree, err := g.Parse(valid)
+	if err != nil {
+		return nil, err
+	}
+
+	terminals := g.terminals()
+	var edits []edit
+	var walk func(node *ASTNode, root bool)
+	walk = func(node *ASTNode, root bool) {
+		if !root && node.End > node.Pos {
+			edits = append(edits,
+				edit{pos: node.Pos, end: node.End},
+				edit{pos: node.End, end: node.End, text: valid[node.Pos:node.End]},
+			)
+		}
+		if node.Type == "TERMINAL" {
+			for _, t := range terminals {
+				if t != node.Value {
+					edits = append(edits, edit{pos: node.Pos, end: node.End, text: t})
+				}
+			}
+		}
+		for _, c := range node.Children {
+			walk(c, false)
+		}
+	}
+	walk(tree, true)
+	r.Shuffle(len(edits), func(i, j int) { edits[i], edits[j] = edits[j], edits[i] })
+
+	var out []string
+	seen := map[string]bool{valid: true}
+	for _, e := range edits {
+		if len(out) >= n {
+			break
+		}
+		mutant := valid[:e.pos] + e.text + valid[e.end:]
+		if seen[mutant] {
+			continue
+		}
+		seen[mutant] = true
+		ok, err := g.Match(mutant)
+		if _, isParseErr := err.(*ParseError); !ok && isParseErr {
+			out = append(out, mutant)
+		}
+	}
+	return out, nil
+}
+
+// terminals lists the distinct non-empty string literals of the grammar in definition order.
+func (g *Grammar) terminals() []string {
+	var out []string
+	seen := map[string]bool{}
+	var walk func(n node)
+	walk = func(n node) {
+		switch t := n.(type) {
+		case *terminal:
+			if t.Value != "" && !seen[t.Value] {
+				seen[t.Value] = true
+				out = append(out, t.Value)
+			}
+		case *sequence:
+			for _, e := range t.Elements {
+				walk(e)
+			}
+		case *choice:
+			for _, o := range t.Options {
+				walk(o)
+			}
+		case *repeat:
+			walk(t.Node)
+		case *optional:
+			walk(t.Node)
+		case *notPredicate:
+			walk(t.Node)
+		case *dropped:
+			walk(t.Node)
+		case *token:
+			walk(t.def.node)
+		case *difference:
+			walk(t.Node)
+			walk(t.Except)
+		}
+	}
+	for _, name := range g.ruleNames() {
+		walk(g.Rules[name].Expr)
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package bnf_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutate(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	mutants, err := g.Mutate(r, "7:38pm", 10)
+	assert.NoError(t, err)
+	assert.Len(t, mutants, 10)
+
+	seen := map[string]bool{}
+	for _, m := range mutants {
+		assert.False(t, seen[m], "duplicate mutant %q", m)
+		seen[m] = true
+
+		ok, err := g.Match(m)
+		assert.False(t, ok, m)
+		assert.Error(t, err)
+	}
+}
+
+func TestMutate_Kinds(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<S> ::= "a" ":" "b"?`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(3, 4))
+	mutants, err := g.Mutate(r, "a:b", 100)
+	assert.NoError(t, err)
+	assert.Contains(t, mutants, ":b")    // dropped required element
+	assert.Contains(t, mutants, "a:bb")  // duplicated optional element
+	assert.Contains(t, mutants, "a::")   // swapped terminal
+	assert.NotContains(t, mutants, "a:") // still valid
+}
+
+func TestMutate_InvalidSeed(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+
+	_, err = g.Mutate(rand.New(rand.NewPCG(1, 2)), "x", 1)
+	assert.Error(t, err)
+}
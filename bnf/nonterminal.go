@@ -1,6 +1,10 @@
 package bnf
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 type nonTerminal struct {
 	Name string
@@ -21,22 +25,71 @@ func (n *nonTerminal) match(ctx *context, pos int) ([]MatchResult, error) {
 	}
 
 	var results []MatchResult
+	seen := map[int]bool{}
 	for _, m := range matches {
-		// Wrap children in a new ASTNode representing this rule
-		node := &ASTNode{
-			Type:     n.Name,
-			Children: m.Nodes,
-			// Value is usually empty for non-terminals unless we want capturing
+		var nodes []*ASTNode
+		switch n.Rule.tree {
+		case treeInline:
+			nodes = m.Nodes
+		case treeHidden, treeLeaf:
+			// the nodes depend on the end only, like for dropped elements
+			if seen[m.End] {
+				continue
+			}
+			seen[m.End] = true
+			if n.Rule.tree == treeLeaf {
+				nodes = []*ASTNode{{Type: n.Name, Value: ctx.input[pos:m.End], Pos: pos, End: m.End}}
+			}
+		default:
+			// Wrap children in a new ASTNode representing this rule
+			nodes = []*ASTNode{{
+				Type:     n.Name,
+				Children: m.Nodes,
+				Pos:      pos,
+				End:      m.End,
+			}}
+		}
+		if ctx.actions != nil && n.Rule.tree != treeInline {
+			for _, node := range nodes {
+				ctx.evaluate(node)
+			}
 		}
 		results = append(results, MatchResult{
 			End:   m.End,
-			Nodes: []*ASTNode{node},
+			Nodes: nodes,
+			hits:  m.hits,
 		})
 	}
 
-	return results, nil
+	return ctx.traced(results, coverKey{rule: n.Name}), nil
 }
 
 func (n *nonTerminal) Expect() []string {
 	return []string{n.Name}
 }
+
+// treeShape is how the matches of a rule appear in parse trees where the rule
+// is used, set by the annotations of the rule.
+type treeShape int
+
+const (
+	treeNode   treeShape = iota // a node of the rule with the nodes of its expression as children
+	treeInline                  // the nodes of its expression in place of the node, @inline
+	treeHidden                  // no nodes at all, @hidden or a name starting with _
+	treeLeaf                    // a node of the rule with the matched text as Value, @leaf or @token
+)
+
+// ruleTree returns the tree shape of the rule from its annotations and name.
+func ruleTree(r *RuleAST) treeShape {
+	switch {
+	case slices.Contains(r.Annotations, "inline"):
+		return treeInline
+	case slices.Contains(r.Annotations, "hidden"):
+		return treeHidden
+	case slices.Contains(r.Annotations, "leaf"), slices.Contains(r.Annotations, "token"):
+		return treeLeaf
+	case strings.HasPrefix(r.Name[strings.LastIndex(r.Name, ".")+1:], "_"):
+		return treeHidden
+	}
+	return treeNode
+}
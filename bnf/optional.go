@@ -6,14 +6,14 @@ type optional struct {
 
 func (o *optional) match(ctx *context, pos int) ([]MatchResult, error) {
 	// we can always match nothing
-	results := []MatchResult{{End: pos, Nodes: nil}}
+	results := ctx.traced([]MatchResult{{End: pos, Nodes: nil}}, coverKey{node: o, branch: 0})
 
 	// try to match Node
 	matches, err := ctx.Match(o.Node, pos)
 	if err != nil {
 		return nil, err
 	}
-	for _, m := range matches {
+	for _, m := range ctx.traced(matches, coverKey{node: o, branch: 1}) {
 		if m.End != pos { // protection against empty progress, although optional usually allows it?
 			// Actually optional *is* the empty match. If node matches something, we return it.
 			results = append(results, m)
@@ -55,3 +55,14 @@ func TestOptionalPlus_Terminal(t *testing.T) {
 	assert.True(t, slices.Contains(ends("aa"), 2))
 	assert.False(t, slices.Contains(ends(""), 0))
 }
+
+func TestOptional_Match(t *testing.T) {
+	// "a"? matches "a" at most once, where it used to repeat like "a"*
+	g, err := LoadGrammarString(`s ::= "a"?`)
+	assert.NoError(t, err)
+
+	for in, want := range map[string]bool{"": true, "a": true, "aa": false} {
+		ok, _ := g.Match(in)
+		assert.Equal(t, want, ok, "%q", in)
+	}
+}
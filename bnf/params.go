@@ -0,0 +1,183 @@
+package bnf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxInstances and maxInstanceName bound the rules created by expandParams,
+// which only grow without end for calls passing ever larger arguments to
+// themselves.
+const (
+	maxInstances    = 1000
+	maxInstanceName = 1000
+)
+
+// expandParams replaces the parameterized rules of ast by a concrete rule for
+// every distinct call, named after the call like sep-list<id,",">. The same
+// call always yields the same name, so instances are shared between uses.
+func expandParams(ast *GrammarAST) (*GrammarAST, error) {
+	x := &expander{templates: map[string]*RuleAST{}, rules: map[string]bool{}, done: map[string]bool{}}
+	for _, r := range ast.Rules {
+		if len(r.Params) > 0 {
+			x.templates[r.Name] = r
+		} else {
+			x.rules[r.Name] = true
+		}
+	}
+	for name := range x.templates {
+		if x.rules[name] {
+			return nil, fmt.Errorf("rule %s is defined both with and without parameters", name)
+		}
+	}
+
+	out := *ast
+	if ast.Skip != nil {
+		expr, err := x.expand(ast.Skip.Expr, nil)
+		if err != nil {
+			return nil, err
+		}
+		skip := *ast.Skip
+		skip.Expr = expr
+		out.Skip = &skip
+	}
+	out.Rules = nil
+	for _, r := range ast.Rules {
+		if len(r.Params) > 0 {
+			continue
+		}
+		expr, err := x.expand(r.Expr, nil)
+		if err != nil {
+			return nil, err
+		}
+		c := *r
+		c.Expr = expr
+		out.Rules = append(out.Rules, &c)
+	}
+	if len(out.Rules) == 0 {
+		return nil, fmt.Errorf("grammar has parameterized rules only")
+	}
+	out.Rules = append(out.Rules, x.instances...)
+	return &out, nil
+}
+
+type expander struct {
+	templates map[string]*RuleAST
+	rules     map[string]bool // names of the rules without parameters
+	done      map[string]bool // instances created or being created
+	instances []*RuleAST
+}
+
+// expand substitutes the arguments of env for parameters in e and turns
+// calls into references to their instances.
+func (x *expander) expand(e ExprAST, env map[string]ExprAST) (ExprAST, error) {
+	switch t := e.(type) {
+	case *IdentAST:
+		if arg, ok := env[t.Name]; ok {
+			return arg, nil
+		}
+		if tmpl, ok := x.templates[t.Name]; ok {
+			return nil, fmt.Errorf("rule %s needs %d arguments, like <%s %s>", t.Name, len(tmpl.Params), t.Name, strings.Join(tmpl.Params, " "))
+		}
+		return t, nil
+	case *CallAST:
+		return x.call(t, env)
+	case *SeqAST:
+		out := &SeqAST{}
+		for _, el := range t.Elements {
+			n, err := x.expand(el, env)
+			if err != nil {
+				return nil, err
+			}
+			out.Elements = append(out.Elements, n)
+		}
+		return out, nil
+	case *ChoiceAST:
+		out := &ChoiceAST{}
+		for _, o := range t.Options {
+			n, err := x.expand(o, env)
+			if err != nil {
+				return nil, err
+			}
+			out.Options = append(out.Options, n)
+		}
+		return out, nil
+	case *RepeatAST:
+		n, err := x.expand(t.Node, env)
+		return &RepeatAST{Node: n, Min: t.Min, Max: t.Max}, err
+	case *NotAST:
+		n, err := x.expand(t.Node, env)
+		return &NotAST{Node: n}, err
+	case *DropAST:
+		n, err := x.expand(t.Node, env)
+		return &DropAST{Node: n}, err
+	case *DiffAST:
+		n, err := x.expand(t.Node, env)
+		if err != nil {
+			return nil, err
+		}
+		except, err := x.expand(t.Except, env)
+		return &DiffAST{Node: n, Except: except}, err
+	case *SepListAST:
+		n, err := x.expand(t.Node, env)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := x.expand(t.Sep, env)
+		return &SepListAST{Node: n, Sep: sep, Min: t.Min, Trailing: t.Trailing}, err
+	}
+	return e, nil
+}
+
+func (x *expander) call(c *CallAST, env map[string]ExprAST) (ExprAST, error) {
+	tmpl, ok := x.templates[c.Name]
+	if !ok {
+		if x.rules[c.Name] {
+			return nil, fmt.Errorf("rule %s takes no arguments", c.Name)
+		}
+		return nil, fmt.Errorf("undefined parameterized rule: %s", c.Name)
+	}
+	if len(c.Args) != len(tmpl.Params) {
+		return nil, fmt.Errorf("rule %s takes %d arguments, got %d", c.Name, len(tmpl.Params), len(c.Args))
+	}
+
+	args := make([]ExprAST, len(c.Args))
+	for i, a := range c.Args {
+		var err error
+		if args[i], err = x.expand(a, env); err != nil {
+			return nil, err
+		}
+	}
+	name := instanceName(c.Name, args)
+	if x.done[name] {
+		return &IdentAST{Name: name}, nil
+	}
+	if len(x.done) >= maxInstances || len(name) > maxInstanceName {
+		return nil, fmt.Errorf("parameterized rules expand to too many rules, does %s call itself with growing arguments?", c.Name)
+	}
+	x.done[name] = true // before expanding the body, which may call itself
+
+	inner := make(map[string]ExprAST, len(args))
+	for i, param := range tmpl.Params {
+		inner[param] = args[i]
+	}
+	inst := &RuleAST{Name: name, Annotations: tmpl.Annotations, Line: tmpl.Line, EndLine: tmpl.EndLine}
+	x.instances = append(x.instances, inst)
+	expr, err := x.expand(tmpl.Expr, inner)
+	if err != nil {
+		return nil, err
+	}
+	inst.Expr = expr
+	return &IdentAST{Name: name}, nil
+}
+
+// instanceName names the instance of a parameterized rule for the arguments,
+// which are expanded already and so free of calls.
+func instanceName(name string, args []ExprAST) string {
+	pr := &printer{}
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = pr.operand(a)
+	}
+	return name + "<" + strings.Join(parts, ",") + ">"
+}
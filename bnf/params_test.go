@@ -0,0 +1,116 @@
+package bnf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParams_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		match   []string
+		reject  []string
+	}{
+		{
+			`<args> ::= "(" <sep-list <id> ","> ")"` + "\n" + `<sep-list X S> ::= X (S X)*` + "\n" + `<id> ::= [a-z]+`,
+			[]string{"(a)", "(a,bc,d)"}, []string{"()", "(a,)", "(a;b)"},
+		},
+		{
+			`<s> ::= <pair "a" <pair "b" "c">>` + "\n" + `<pair A B> ::= A B`,
+			[]string{"abc"}, []string{"ab", "acb"},
+		},
+		{
+			`<s> ::= <nest "(" ")">` + "\n" + `<nest L R> ::= L <nest L R>? R`,
+			[]string{"()", "((()))"}, []string{"(()", ""},
+		},
+		{
+			`<s> ::= <list ("x" | "y")*>` + "\n" + `<list X> ::= "[" X "]"`,
+			[]string{"[]", "[xyx]"}, []string{"[z]"},
+		},
+	}
+	for _, tt := range tests {
+		g, err := bnf.LoadGrammarString(tt.grammar)
+		if !assert.NoError(t, err, tt.grammar) {
+			continue
+		}
+		for _, in := range tt.match {
+			ok, err := g.Match(in)
+			assert.True(t, ok, "%s on %q", tt.grammar, in)
+			assert.NoError(t, err)
+		}
+		for _, in := range tt.reject {
+			ok, _ := g.Match(in)
+			assert.False(t, ok, "%s on %q", tt.grammar, in)
+		}
+	}
+}
+
+func TestParams_Instances(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<s>             ::= <sep-list <id> ","> ";" <sep-list <id> ",">
+<sep-list X S>  ::= X (S X)*
+<id>            ::= [a-z]+
+`)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("a,b;c")
+	assert.NoError(t, err)
+	assert.Equal(t, `sep-list<id,",">`, tree.Children[0].Type)
+	assert.Equal(t, `sep-list<id,",">`, tree.Children[2].Type)
+	assert.Equal(t, "id", tree.Children[0].Children[0].Type)
+}
+
+func TestParams_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		err     string
+	}{
+		{`<s> ::= <pair "a">` + "\n" + `<pair A B> ::= A B`, "rule pair takes 2 arguments, got 1"},
+		{`<s> ::= <pair "a" "b">`, "undefined parameterized rule: pair"},
+		{`<s> ::= <t "a">` + "\n" + `<t> ::= "b"`, "rule t takes no arguments"},
+		{`<s> ::= <pair>` + "\n" + `<pair A B> ::= A B`, "rule pair needs 2 arguments, like <pair A B>"},
+		{`<s> ::= <t "a">` + "\n" + `<t X> ::= X` + "\n" + `<t> ::= "b"`, "rule t is defined both with and without parameters"},
+		{`<s> ::= <grow "a">` + "\n" + `<grow X> ::= X | <grow (X X)>`, "call itself with growing arguments"},
+		{`<pair A A> ::= A`, "duplicate parameter A of rule pair"},
+		{`<s> ::= <pair "a"`, "unterminated call of rule <pair"},
+	}
+	for _, tt := range tests {
+		_, err := bnf.LoadGrammarString(tt.grammar)
+		assert.ErrorContains(t, err, tt.err, tt.grammar)
+	}
+}
+
+func TestParams_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `<s>            ::= <sep-list <id> ","> <opt ("x" | "y")>` + "\n" +
+		`<sep-list X S> ::= X (S X)*` + "\n" +
+		`<opt X>        ::= X?` + "\n" +
+		`<id>           ::= "a"` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(src))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: bnf.ExportW3C})
+	assert.NoError(t, err)
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, `s           ::= sep-list_id opt_x_y`, lines[0])
+	assert.Contains(t, lines, `sep-list_id ::= id ("," id)*`)
+}
@@ -17,10 +17,18 @@ type ParseError struct {
 	Found    string
 
 	Width int // number of characters to highlight
+
+	Err error // error returned by a semantic action, see Grammar.Evaluate
 }
 
 // Error returns a basic string description of the parse error.
 func (err *ParseError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf(
+			"  Action error at line %d, col %d\n  While evaluating rule: %s\n  %v\n",
+			err.Line, err.Column, err.RuleStack, err.Err,
+		)
+	}
 	return fmt.Sprintf(
 		"  Parse error at line %d, col %d\n  While matching rule: %s\n  Expected: %v\n  Found: %s\n",
 		err.Line, err.Column, err.RuleStack, err.Expected, err.Found,
@@ -31,9 +39,13 @@ func (err *ParseError) Error() string {
 func (e *ParseError) Pretty(input string) string {
 	var sb strings.Builder
 
+	kind := "Parse"
+	if e.Err != nil {
+		kind = "Action"
+	}
 	sb.WriteString(fmt.Sprintf(
-		"Parse error at line %d, column %d, rule %s\n\n",
-		e.Line, e.Column, e.RuleStack,
+		"%s error at line %d, column %d, rule %s\n\n",
+		kind, e.Line, e.Column, e.RuleStack,
 	))
 
 	line := extractLine(input, e.Pos)
@@ -59,9 +71,19 @@ func (e *ParseError) Pretty(input string) string {
 		sb.WriteString(strings.Join(terms, ", "))
 	}
 
+	if e.Err != nil {
+		sb.WriteString("\nError: ")
+		sb.WriteString(e.Err.Error())
+	}
+
 	return sb.String()
 }
 
+// Unwrap returns the error of the semantic action, if any.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 func extractLine(input string, pos int) string {
 	runes := []rune(input)
 
@@ -3,6 +3,9 @@ package bnf
 import (
 	"fmt"
 	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
 )
 
 // Parser converts a stream of BNF tokens into a GrammarAST.
@@ -10,6 +13,11 @@ type Parser struct {
 	lx   *Lexer
 	look Token
 	peek Token
+
+	ahead    []Token // tokens lexed beyond peek, see tokenAt
+	aheadErr error   // lexer error after the tokens of ahead
+
+	lastLine int // line of the last consumed token
 }
 
 // NewParser creates a new Parser for the given reader.
@@ -35,36 +43,186 @@ func (p *Parser) eat(t TokenType) (Token, error) {
 		return Token{}, fmt.Errorf("unexpected token: %s, expected: %d", p.look.Text, t)
 	}
 	tok := p.look
+	p.lastLine = tok.Line
 	p.look = p.peek
 	var err error
-	p.peek, err = p.lx.Next()
+	p.peek, err = p.next()
 	if err != nil {
 		return Token{}, err
 	}
 	return tok, nil
 }
 
+func (p *Parser) next() (Token, error) {
+	if len(p.ahead) > 0 {
+		tok := p.ahead[0]
+		p.ahead = p.ahead[1:]
+		return tok, nil
+	}
+	if p.aheadErr != nil {
+		return Token{}, p.aheadErr
+	}
+	return p.lx.Next()
+}
+
+// tokenAt returns the i-th token from the current one without consuming it.
+// A lexer error shows up as EOF here and is returned once the token is eaten.
+func (p *Parser) tokenAt(i int) Token {
+	switch i {
+	case 0:
+		return p.look
+	case 1:
+		return p.peek
+	}
+	for len(p.ahead) < i-1 && p.aheadErr == nil {
+		tok, err := p.lx.Next()
+		if err != nil {
+			p.aheadErr = err
+			break
+		}
+		p.ahead = append(p.ahead, tok)
+	}
+	if i-2 < len(p.ahead) {
+		return p.ahead[i-2]
+	}
+	return Token{Type: EOF}
+}
+
 // ParseGrammar parses the input into a complete GrammarAST.
 func (p *Parser) ParseGrammar() (*GrammarAST, error) {
-	var rules []*RuleAST
+	ast := &GrammarAST{}
 	for p.look.Type != EOF {
+		if p.look.Type == DIRECTIVE {
+			if err := p.parseDirective(ast); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		// process rule otherwise
 		r, err := p.parseRule()
 		if err != nil {
 			return nil, err
 		}
-		rules = append(rules, r)
+		ast.Rules = append(ast.Rules, r)
+	}
+	if slices.ContainsFunc(ast.Rules, func(r *RuleAST) bool { return r.Name == "EOF" }) {
+		// grammars written before EOF meant the end of input keep their rule
+		for _, r := range ast.Rules {
+			r.Expr = mapExpr(r.Expr, func(e ExprAST) ExprAST {
+				if end, ok := e.(*EndAST); ok && end.word {
+					return &IdentAST{Name: "EOF"}
+				}
+				return e
+			})
+		}
+	}
+	ast.Comments = p.lx.Comments()
+	return ast, nil
+}
+
+// ruleAnnotations are the directives written before a rule, like @lexical.
+var ruleAnnotations = []string{"lexical", "token", "leaf", "inline", "hidden"}
+
+// treeAnnotations are the annotations deciding how a rule appears in parse
+// trees, a rule can have one of them at most.
+var treeAnnotations = []string{"token", "leaf", "inline", "hidden"}
+
+func (p *Parser) parseDirective(ast *GrammarAST) error {
+	tok, err := p.eat(DIRECTIVE)
+	if err != nil {
+		return err
+	}
+	switch tok.Text {
+	case "import":
+		imp := &ImportAST{Line: tok.Line}
+		if p.look.Type == IDENT {
+			alias, _ := p.eat(IDENT)
+			if strings.Contains(alias.Text, ".") {
+				return fmt.Errorf("invalid import alias: %s", alias.Text)
+			}
+			imp.Alias = alias.Text
+		}
+		path, err := p.eat(STRING)
+		if err != nil {
+			return fmt.Errorf("@import expects a quoted file name")
+		}
+		imp.Path = path.Text
+		ast.Imports = append(ast.Imports, imp)
+		return nil
+	case "skip":
+		if ast.Skip != nil {
+			return fmt.Errorf("duplicate @skip directive")
+		}
+		if _, err := p.eat(ASSIGN); err != nil {
+			return fmt.Errorf("@skip expects ::= followed by an expression")
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return err
+		}
+		ast.Skip = &RuleAST{Expr: expr, Line: tok.Line, EndLine: p.lastLine}
+		return nil
+	}
+	if !slices.Contains(ruleAnnotations, tok.Text) {
+		return fmt.Errorf("unknown directive: @%s", tok.Text)
+	}
+
+	// annotations of the rule following them
+	annotations := []string{tok.Text}
+	for p.look.Type == DIRECTIVE && slices.Contains(ruleAnnotations, p.look.Text) {
+		a, _ := p.eat(DIRECTIVE)
+		if !slices.Contains(annotations, a.Text) {
+			annotations = append(annotations, a.Text)
+		}
+	}
+	r, err := p.parseRule()
+	if err != nil {
+		return err
 	}
-	return &GrammarAST{Rules: rules}, nil
+	var shaping []string
+	for _, a := range annotations {
+		if slices.Contains(treeAnnotations, a) {
+			shaping = append(shaping, "@"+a)
+		}
+	}
+	if len(shaping) > 1 {
+		return fmt.Errorf("rule %s cannot be both %s", r.Name, strings.Join(shaping, " and "))
+	}
+	r.Annotations = annotations
+	r.Line = tok.Line
+	ast.Rules = append(ast.Rules, r)
+	return nil
 }
 
 func (p *Parser) parseRule() (*RuleAST, error) {
-	tok, err := p.eat(IDENT)
+	bracketed := p.look.Type == NT_IDENT || p.look.Type == NT_CALL
+	tok, err := p.eat(p.look.Type)
 	if err != nil {
 		return nil, err
 	}
+	if tok.Type != IDENT && tok.Type != NT_IDENT && tok.Type != NT_CALL {
+		return nil, fmt.Errorf("unexpected token: %s, expected a rule name", tok.Text)
+	}
 	name := tok.Text
 
+	var params []string
+	if tok.Type == NT_CALL {
+		for p.look.Type == IDENT {
+			param, _ := p.eat(IDENT)
+			if slices.Contains(params, param.Text) {
+				return nil, fmt.Errorf("duplicate parameter %s of rule %s", param.Text, name)
+			}
+			params = append(params, param.Text)
+		}
+		if _, err := p.eat(RANGLE); err != nil {
+			return nil, fmt.Errorf("parameters of rule %s must be plain names", name)
+		}
+		if len(params) == 0 {
+			return nil, fmt.Errorf("rule <%s > has no parameters", name)
+		}
+	}
+
 	if _, err := p.eat(ASSIGN); err != nil {
 		return nil, err
 	}
@@ -74,7 +232,7 @@ func (p *Parser) parseRule() (*RuleAST, error) {
 		return nil, err
 	}
 
-	return &RuleAST{Name: name, Expr: expr}, nil
+	return &RuleAST{Name: name, Params: params, Expr: expr, Bracketed: bracketed, Line: tok.Line, EndLine: p.lastLine}, nil
 }
 
 func (p *Parser) parseExpr() (ExprAST, error) {
@@ -123,8 +281,8 @@ func (p *Parser) parseSeq() (ExprAST, error) {
 		}
 
 		switch p.look.Type {
-		case IDENT, STRING, REGEX, LPAREN:
-			e, err := p.parseFactor()
+		case IDENT, NT_IDENT, NT_CALL, STRING, ISTRING, REGEX, CLASS, LPAREN, DOT, DOLLAR, BANG, DROP:
+			e, err := p.parseTerm()
 			if err != nil {
 				return nil, err
 			}
@@ -144,7 +302,51 @@ func singleOrSeq(elems []ExprAST) ExprAST {
 	return &SeqAST{Elements: elems}
 }
 
+// parseTerm parses a factor with the exceptions subtracted from it, binding
+// tighter than sequences like in ISO EBNF.
+func (p *Parser) parseTerm() (ExprAST, error) {
+	e, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.look.Type == MINUS || p.look.Type == PERCENT {
+		op := p.look
+		p.eat(op.Type)
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		if op.Type == MINUS {
+			e = &DiffAST{Node: e, Except: right}
+			continue
+		}
+		list := &SepListAST{Node: e, Sep: right, Min: 1, Trailing: strings.HasPrefix(op.Text, "%%")}
+		if strings.HasSuffix(op.Text, "*") {
+			list.Min = 0
+		}
+		e = list
+	}
+	return e, nil
+}
+
 func (p *Parser) parseFactor() (ExprAST, error) {
+	if p.look.Type == BANG {
+		p.eat(BANG)
+		e, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &NotAST{Node: e}, nil
+	}
+	if p.look.Type == DROP {
+		p.eat(DROP)
+		e, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &DropAST{Node: e}, nil
+	}
+
 	atom, err := p.parseAtom()
 	if err != nil {
 		return nil, err
@@ -174,6 +376,10 @@ func (p *Parser) parseAtom() (ExprAST, error) {
 		if err != nil {
 			return nil, err
 		}
+		if tok.Text == "EOF" {
+			// unless the grammar defines a rule EOF, see ParseGrammar
+			return &EndAST{word: true}, nil
+		}
 		return &IdentAST{Name: tok.Text}, nil
 	case NT_IDENT:
 		tok, err := p.eat(NT_IDENT)
@@ -181,18 +387,41 @@ func (p *Parser) parseAtom() (ExprAST, error) {
 			return nil, err
 		}
 		return &IdentAST{Name: tok.Text}, nil
+	case NT_CALL:
+		return p.parseCall()
 	case STRING:
 		tok, err := p.eat(STRING)
 		if err != nil {
 			return nil, err
 		}
+		if p.look.Type == DOTDOT {
+			return p.parseRange(tok.Text)
+		}
 		return &StringAST{Value: tok.Text}, nil
+	case ISTRING:
+		tok, err := p.eat(ISTRING)
+		if err != nil {
+			return nil, err
+		}
+		return &StringAST{Value: tok.Text, IgnoreCase: true}, nil
+	case CLASS:
+		tok, err := p.eat(CLASS)
+		if err != nil {
+			return nil, err
+		}
+		return parseCharClass(tok.Text)
 	case REGEX:
 		tok, err := p.eat(REGEX)
 		if err != nil {
 			return nil, err
 		}
 		return &RegexAST{Pattern: tok.Text}, nil
+	case DOT:
+		p.eat(DOT)
+		return &AnyAST{}, nil
+	case DOLLAR:
+		p.eat(DOLLAR)
+		return &EndAST{}, nil
 	case LPAREN:
 		if _, err := p.eat(LPAREN); err != nil {
 			return nil, err
@@ -209,6 +438,62 @@ func (p *Parser) parseAtom() (ExprAST, error) {
 	return nil, fmt.Errorf("unexpected token in atom: %v", p.look)
 }
 
+// parseCall parses the arguments of a parameterized rule up to the closing >.
+func (p *Parser) parseCall() (ExprAST, error) {
+	tok, err := p.eat(NT_CALL)
+	if err != nil {
+		return nil, err
+	}
+	call := &CallAST{Name: tok.Text}
+	for p.look.Type != RANGLE {
+		if p.look.Type == EOF {
+			return nil, fmt.Errorf("unterminated call of rule <%s", tok.Text)
+		}
+		arg, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+	}
+	p.eat(RANGLE)
+	if len(call.Args) == 0 {
+		return nil, fmt.Errorf("call of rule <%s > has no arguments", tok.Text)
+	}
+	return call, nil
+}
+
+// parseRange parses the rest of a character range 'a'..'z' starting at lo.
+func (p *Parser) parseRange(lo string) (ExprAST, error) {
+	if _, err := p.eat(DOTDOT); err != nil {
+		return nil, err
+	}
+	tok, err := p.eat(STRING)
+	if err != nil {
+		return nil, fmt.Errorf("character range %q.. expects a quoted character", lo)
+	}
+	hi := tok.Text
+	if utf8.RuneCountInString(lo) != 1 || utf8.RuneCountInString(hi) != 1 {
+		return nil, fmt.Errorf("character range %q..%q must be between single characters", lo, hi)
+	}
+	l, _ := utf8.DecodeRuneInString(lo)
+	h, _ := utf8.DecodeRuneInString(hi)
+	if l > h {
+		return nil, fmt.Errorf("invalid character range %q..%q", lo, hi)
+	}
+	return &CharClassAST{Ranges: []RuneRange{{Lo: l, Hi: h}}}, nil
+}
+
 func (p *Parser) isRuleStart() bool {
-	return p.look.Type == IDENT && p.peek.Type == ASSIGN
+	switch p.look.Type {
+	case IDENT, NT_IDENT:
+		return p.peek.Type == ASSIGN
+	case NT_CALL:
+		// <name X Y> ::= defines a parameterized rule, anything else calls one
+		i := 1
+		for p.tokenAt(i).Type == IDENT {
+			i++
+		}
+		return p.tokenAt(i).Type == RANGLE && p.tokenAt(i+1).Type == ASSIGN
+	}
+	return false
 }
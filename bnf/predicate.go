@@ -0,0 +1,66 @@
+package bnf
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// anyRune matches any single character, written as . in grammars.
+type anyRune struct{}
+
+var anyRuneRegex = stdPattern(`(?s:.)`)
+
+func (a *anyRune) match(ctx *context, pos int) ([]MatchResult, error) {
+	_, size := utf8.DecodeRuneInString(ctx.input[pos:])
+	if size == 0 {
+		return nil, nil
+	}
+	return []MatchResult{{
+		End:   pos + size,
+		Nodes: []*ASTNode{{Type: "REGEX", Value: ctx.input[pos : pos+size], Pos: pos, End: pos + size}},
+	}}, nil
+}
+
+func (a *anyRune) Expect() []string {
+	return []string{"any character"}
+}
+
+func (a *anyRune) regex() *Regex {
+	return anyRuneRegex
+}
+
+// endOfInput matches the empty string at the end of the input only, written
+// as EOF or $ in grammars.
+type endOfInput struct{}
+
+func (e *endOfInput) match(ctx *context, pos int) ([]MatchResult, error) {
+	if pos < len(ctx.input) {
+		return nil, nil
+	}
+	return []MatchResult{{End: pos}}, nil
+}
+
+func (e *endOfInput) Expect() []string {
+	return []string{"EOF"}
+}
+
+// notPredicate is a negative lookahead: it matches the empty string when Node
+// does not match at the same position, and fails otherwise.
+type notPredicate struct {
+	Node node
+}
+
+func (n *notPredicate) match(ctx *context, pos int) ([]MatchResult, error) {
+	matches, err := ctx.lookahead(n.Node, pos)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) > 0 {
+		return nil, nil
+	}
+	return []MatchResult{{End: pos}}, nil
+}
+
+func (n *notPredicate) Expect() []string {
+	return []string{"not " + strings.Join(n.Node.Expect(), " or ")}
+}
@@ -0,0 +1,177 @@
+package bnf_test
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredicates_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		match   []string
+		reject  []string
+	}{
+		{`<c> ::= .`, []string{"a", "\n", "ż"}, []string{"", "ab"}},
+		{`<c> ::= "#" (!"\n" .)* "\n"`, []string{"#\n", "# a b\n"}, []string{"# a\nb\n", "# a"}},
+		{`<c> ::= "/*" (!"*/" .)* "*/"`, []string{"/**/", "/* a * / */"}, []string{"/* */ */"}},
+		{`<c> ::= "a" EOF`, []string{"a"}, []string{"ab"}},
+		{`<c> ::= "a" $ "b"?`, []string{"a"}, []string{"ab"}},
+		{`<c> ::= <word> ("," <word>)*` + "\n" + `<word> ::= !"end" [a-z]+`, []string{"a,den", "x"}, []string{"end", "a,ends"}},
+		{`<c> ::= "a" <EOF>` + "\n" + `<EOF> ::= "!"`, []string{"a!"}, []string{"a"}},
+		{`s ::= "a" EOF ("c" | $)` + "\n" + `EOF ::= "b"`, []string{"ab", "abc"}, []string{"a", "abcc"}},
+	}
+	for _, tt := range tests {
+		g, err := bnf.LoadGrammarString(tt.grammar)
+		if !assert.NoError(t, err, tt.grammar) {
+			continue
+		}
+		for _, in := range tt.match {
+			ok, err := g.Match(in)
+			assert.True(t, ok, "%s on %q", tt.grammar, in)
+			assert.NoError(t, err)
+		}
+		for _, in := range tt.reject {
+			ok, _ := g.Match(in)
+			assert.False(t, ok, "%s on %q", tt.grammar, in)
+		}
+	}
+}
+
+func TestPredicates_Parse(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<comment> ::= "#" (!"\n" .)* "\n"`)
+	assert.NoError(t, err)
+
+	// the lookahead and the anchor leave no nodes in the tree
+	tree, err := g.Parse("#ab\n")
+	assert.NoError(t, err)
+	if assert.Len(t, tree.Children, 4) {
+		assert.Equal(t, "REGEX", tree.Children[1].Type)
+		assert.Equal(t, "a", tree.Children[1].Value)
+	}
+
+	// failures of the lookahead itself are not expectations
+	_, err = g.Parse("#ab")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.ElementsMatch(t, []string{"any character", `"\n"`}, perr.Expected)
+	}
+
+	g, err = bnf.LoadGrammarString(`<s> ::= "a"+ EOF`)
+	assert.NoError(t, err)
+	_, err = g.Parse("aab")
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 2, perr.Pos)
+		assert.ElementsMatch(t, []string{`"a"`, "EOF"}, perr.Expected)
+	}
+}
+
+func TestPredicates_AfterName(t *testing.T) {
+	t.Parallel()
+
+	// the wildcard written right after a rule name
+	g, err := bnf.LoadGrammarString("s ::= x.\nx ::= \"a\"")
+	assert.NoError(t, err)
+	ok, err := g.Match("ab")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, _ = g.Match("a")
+	assert.False(t, ok)
+
+	// failures within the lookahead are not merged into an earlier
+	// expectation at the same position either
+	g, err = bnf.LoadGrammarString(`s ::= "a" ("b" | !"c" "d")`)
+	assert.NoError(t, err)
+	_, err = g.Parse("ax")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 1, perr.Pos)
+		assert.ElementsMatch(t, []string{`"b"`, `"d"`}, perr.Expected)
+	}
+
+	// but a rule first tried in a lookahead reports them when matched again
+	g, err = bnf.LoadGrammarString("s ::= !x \"a\" \"c\" | x\nx ::= \"a\" \"b\"")
+	assert.NoError(t, err)
+	_, err = g.Parse("ad")
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 1, perr.Pos)
+		assert.ElementsMatch(t, []string{`"b"`, `"c"`}, perr.Expected)
+	}
+}
+
+func TestPredicates_Generate(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<s>       ::= <comment>+ EOF
+<comment> ::= "/*" (!"*/" .)* "*/" | "#" (![a-z] .)* "\n"
+`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		s, err := g.Generate(r, bnf.GenerateOptions{MaxRepeat: 6})
+		if !assert.NoError(t, err) {
+			break
+		}
+		ok, _ := g.Match(s)
+		assert.True(t, ok, "%q", s)
+	}
+
+	g, err = bnf.LoadGrammarString(`<s> ::= (!"ab" [a-c])+ EOF`)
+	assert.NoError(t, err)
+	out, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "aa", "ac", "ba", "bb", "bc", "ca", "cb", "cc"}, out)
+}
+
+func TestPredicates_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `comment ::= "#" (!("\n" | EOF) .)* ("\n" | EOF) | !(!"/")+ "x"` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	// with a rule EOF the end of input is written $
+	src = `s   ::= "a" EOF $` + "\n" + `EOF ::= "b"` + "\n"
+	out, err = bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(`comment ::= "#" (!"\n" .)* $`))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	want := map[string]string{
+		bnf.ExportABNF:  `comment = "#" *(<not %xA> %x0-10FFFF) <end of input>`,
+		bnf.ExportANTLR: `comment : '#' (/* not '\n' */ REGEX1)* EOF ;`,
+		bnf.ExportPEG:   `comment <- "#" (!"\n" .)* !.`,
+	}
+	for format, line := range want {
+		var buf bytes.Buffer
+		warnings, err := bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: format})
+		assert.NoError(t, err)
+		got := ""
+		for l := range strings.Lines(buf.String()) {
+			if strings.HasPrefix(l, "comment ") {
+				got = strings.TrimSpace(l)
+			}
+		}
+		assert.Equal(t, line, got, format)
+		if format == bnf.ExportABNF {
+			assert.Contains(t, warnings, "rule <comment>: negative lookahead cannot be expressed in ABNF")
+			assert.Contains(t, warnings, "rule <comment>: end of input cannot be expressed in ABNF")
+		}
+	}
+}
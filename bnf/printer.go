@@ -0,0 +1,302 @@
+package bnf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineWidth is the width after which a rule is printed with one alternative per line.
+const maxLineWidth = 80
+
+// Format parses a grammar source and returns it in canonical form, the grammar
+// counterpart of go/format.Source.
+func Format(src []byte) ([]byte, error) {
+	p, err := NewParser(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	ast, err := p.ParseGrammar()
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := PrintGrammar(&out, ast); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// PrintGrammar writes the grammar in canonical form: rule names written the
+// way most rules of the grammar are (<name> or bare), ::= aligned within groups
+// of rules not separated by blank lines, literals double quoted and the
+// alternatives of long rules one per line. Comments and single blank lines
+// between rules are kept, comments inside a rule move above it unless the rule
+// fits a single line with one trailing comment.
+func PrintGrammar(w io.Writer, ast *GrammarAST) error {
+	pr := &printer{
+		bracketed: bracketedStyle(ast),
+		skip:      ast.Skip,
+		eofRule:   slices.ContainsFunc(ast.Rules, func(r *RuleAST) bool { return r.Name == "EOF" }),
+	}
+
+	type item struct {
+		line, end int
+		comment   *Comment
+		imp       *ImportAST
+		rule      *RuleAST
+		inner     []Comment // comments within the rule
+	}
+	var items []item
+	for _, imp := range ast.Imports {
+		items = append(items, item{line: imp.Line, end: imp.Line, imp: imp})
+	}
+	if ast.Skip != nil {
+		items = append(items, item{line: ast.Skip.Line, end: ast.Skip.EndLine, rule: ast.Skip})
+	}
+	for _, r := range ast.Rules {
+		items = append(items, item{line: r.Line, end: r.EndLine, rule: r})
+	}
+	for _, c := range ast.Comments {
+		i := slices.IndexFunc(items, func(it item) bool {
+			return it.rule != nil && it.line <= c.Line && c.Line <= it.end
+		})
+		if i >= 0 {
+			items[i].inner = append(items[i].inner, c)
+		} else {
+			items = append(items, item{line: c.Line, end: c.Line, comment: &c})
+		}
+	}
+	slices.SortStableFunc(items, func(a, b item) int { return a.line - b.line })
+
+	// split into blocks at blank lines, aligning ::= within each block
+	var out strings.Builder
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && items[end].line <= items[end-1].end+1 {
+			end++
+		}
+
+		width := 0
+		for _, it := range items[start:end] {
+			if it.rule != nil {
+				width = max(width, utf8.RuneCountInString(pr.head(it.rule)))
+			}
+		}
+		for _, it := range items[start:end] {
+			if it.comment != nil {
+				out.WriteString(it.comment.Text + "\n")
+				continue
+			}
+			if it.imp != nil {
+				out.WriteString(pr.importLine(it.imp) + "\n")
+				continue
+			}
+			trailing := ""
+			if len(it.inner) == 1 && it.inner[0].Inline {
+				trailing = it.inner[0].Text
+			}
+			lines := pr.rule(it.rule, width)
+			if trailing != "" && len(lines) > 1 {
+				trailing = ""
+			}
+			if trailing == "" {
+				for _, c := range it.inner {
+					out.WriteString(c.Text + "\n")
+				}
+			} else {
+				lines[0] += " " + trailing
+			}
+			for _, l := range lines {
+				out.WriteString(strings.TrimRight(l, " ") + "\n")
+			}
+		}
+
+		if end < len(items) {
+			out.WriteString("\n")
+		}
+		start = end
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// bracketedStyle tells whether rule names are printed as <name>: when most
+// rules are written so or some name is not a valid bare identifier.
+func bracketedStyle(ast *GrammarAST) bool {
+	bracketed := 0
+	for _, r := range ast.Rules {
+		if r.Bracketed {
+			bracketed++
+		}
+		first, _ := utf8.DecodeRuneInString(r.Name)
+		if !isIdentStart(first) {
+			return true
+		}
+	}
+	return 2*bracketed >= len(ast.Rules)
+}
+
+type printer struct {
+	bracketed bool
+	params    []string // parameters of the rule being printed, never bracketed
+	skip      *RuleAST // the @skip directive, printed like a rule
+	eofRule   bool     // a rule is named EOF, the end of input is written $
+}
+
+func (pr *printer) importLine(imp *ImportAST) string {
+	if imp.Alias != "" {
+		return "@import " + imp.Alias + " " + quote(imp.Path)
+	}
+	return "@import " + quote(imp.Path)
+}
+
+func (pr *printer) name(name string) string {
+	if pr.bracketed && !slices.Contains(pr.params, name) {
+		return "<" + name + ">"
+	}
+	return name
+}
+
+// head returns the name of the rule together with its parameters, if any.
+func (pr *printer) head(r *RuleAST) string {
+	if r == pr.skip {
+		return "@skip"
+	}
+	name := pr.name(r.Name)
+	if len(r.Params) > 0 {
+		name = "<" + r.Name + " " + strings.Join(r.Params, " ") + ">"
+	}
+	for _, a := range slices.Backward(r.Annotations) {
+		name = "@" + a + " " + name
+	}
+	return name
+}
+
+// rule returns the lines of the rule with its name padded to width.
+func (pr *printer) rule(r *RuleAST, width int) []string {
+	name := pr.head(r)
+	pr.params = r.Params
+	prefix := name + strings.Repeat(" ", width-utf8.RuneCountInString(name)) + " ::= "
+	line := prefix + pr.expr(r.Expr)
+
+	c, ok := r.Expr.(*ChoiceAST)
+	if !ok || utf8.RuneCountInString(line) <= maxLineWidth {
+		return []string{line}
+	}
+	lines := []string{prefix + pr.expr(c.Options[0])}
+	indent := strings.Repeat(" ", utf8.RuneCountInString(prefix)-2)
+	for _, o := range c.Options[1:] {
+		lines = append(lines, indent+"| "+pr.expr(o))
+	}
+	return lines
+}
+
+func (pr *printer) expr(e ExprAST) string {
+	switch t := e.(type) {
+	case *ChoiceAST:
+		parts := make([]string, len(t.Options))
+		for i, o := range t.Options {
+			parts[i] = pr.expr(o)
+			if _, ok := o.(*ChoiceAST); ok {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+		return strings.Join(parts, " | ")
+	case *SeqAST:
+		parts := make([]string, len(t.Elements))
+		for i, el := range t.Elements {
+			parts[i] = pr.expr(el)
+			switch el.(type) {
+			case *ChoiceAST, *SeqAST:
+				parts[i] = "(" + parts[i] + ")"
+			case *DropAST:
+				// after an operand - would be read as a difference
+				if i > 0 {
+					parts[i] = "(" + parts[i] + ")"
+				}
+			}
+		}
+		return strings.Join(parts, " ")
+	case *RepeatAST:
+		op := "*"
+		switch {
+		case t.Max == 1:
+			op = "?"
+		case t.Min > 0:
+			op = "+"
+		}
+		return pr.operand(t.Node) + op
+	case *IdentAST:
+		return pr.name(t.Name)
+	case *StringAST:
+		if t.IgnoreCase {
+			return quote(t.Value) + "i"
+		}
+		return quote(t.Value)
+	case *RegexAST:
+		return "/" + t.Pattern + "/"
+	case *CharClassAST:
+		return t.String()
+	case *BuiltinAST:
+		if b := lookupBuiltin(t.Name); b != nil {
+			return "/" + b.re.Re.String() + "/"
+		}
+	case *AnyAST:
+		return "."
+	case *EndAST:
+		if pr.eofRule {
+			return "$"
+		}
+		return "EOF"
+	case *NotAST:
+		return "!" + pr.operand(t.Node)
+	case *DropAST:
+		return "-" + pr.operand(t.Node)
+	case *CallAST:
+		parts := []string{"<" + t.Name}
+		for _, a := range t.Args {
+			parts = append(parts, pr.operand(a))
+		}
+		return strings.Join(parts, " ") + ">"
+	case *DiffAST:
+		return pr.infix(t.Node) + " - " + pr.operand(t.Except)
+	case *SepListAST:
+		op := "%"
+		if t.Trailing {
+			op = "%%"
+		}
+		if t.Min == 0 {
+			op += "*"
+		}
+		return pr.infix(t.Node) + " " + op + " " + pr.operand(t.Sep)
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+// infix returns the left operand of - and %, which are left-associative.
+func (pr *printer) infix(e ExprAST) string {
+	switch e.(type) {
+	case *DiffAST, *SepListAST:
+		return pr.expr(e)
+	}
+	return pr.operand(e)
+}
+
+func (pr *printer) operand(e ExprAST) string {
+	switch e.(type) {
+	case *ChoiceAST, *SeqAST, *NotAST, *DropAST, *DiffAST, *SepListAST:
+		return "(" + pr.expr(e) + ")"
+	}
+	return pr.expr(e)
+}
+
+// quote writes a literal in double quotes with the escapes the lexer understands.
+func quote(s string) string {
+	return strconv.Quote(s)
+}
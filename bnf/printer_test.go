@@ -0,0 +1,108 @@
+package bnf_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	src := `# Header comment
+; second
+
+expr::=expr '+' term|term   # trailing
+  term ::= "a" // inline
+    | 'b' # inner
+// between
+
+<x> ::= "q\"" /a\/b/ ( "a" | ("b" "c") )* ("d"|"e")?
+
+# end
+`
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, `# Header comment
+; second
+
+expr ::= expr "+" term | term # trailing
+// inline
+# inner
+term ::= "a" | "b"
+// between
+
+x ::= "q\"" /a\/b/ ("a" | "b" "c")* ("d" | "e")?
+
+# end
+`, string(out))
+
+	again, err := bnf.Format(out)
+	assert.NoError(t, err)
+	assert.Equal(t, string(out), string(again))
+}
+
+func TestFormat_LongRule(t *testing.T) {
+	t.Parallel()
+
+	src, err := os.ReadFile("../examples/postal.bnf")
+	assert.NoError(t, err)
+	out, err := bnf.Format(src)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(out), `<postal-address>  ::= <name-part> <street-address> <zip-part>
+<name-part>       ::= <personal-part> <space> <last-name> <opt-suffix-part> <EOL>
+                    | <personal-part> <name-part>
+`)
+	assert.Contains(t, string(out), `<personal-part>   ::= <first-name> | <initial> "."
+`)
+
+	// the formatted grammar is the same grammar
+	g, err := bnf.LoadGrammarString(string(out))
+	assert.NoError(t, err)
+	ok, err := g.Match("John Smith\n123 Main St\nSpringfield, MA 02139\n")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestFormat_NameStyle(t *testing.T) {
+	t.Parallel()
+
+	out, err := bnf.Format([]byte("<a> ::= b c\nb ::= \"b\"\n<c> ::= \"c\"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<a> ::= <b> <c>\n<b> ::= \"b\"\n<c> ::= \"c\"\n", string(out))
+
+	out, err = bnf.Format([]byte("<a> ::= b c\nb ::= \"b\"\nc ::= \"c\"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a ::= b c\nb ::= \"b\"\nc ::= \"c\"\n", string(out))
+
+	// names which cannot be bare force <>
+	out, err = bnf.Format([]byte("a ::= <1st>\n<1st> ::= \"x\"\nb ::= a\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<a>   ::= <1st>\n<1st> ::= \"x\"\n<b>   ::= <a>\n", string(out))
+}
+
+func TestFormat_CharClasses(t *testing.T) {
+	t.Parallel()
+
+	out, err := bnf.Format([]byte(`id ::= 'a'..'z' [a-z0-9\-_]* [^"\n] \p{L} \P{Lu}` + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, `id ::= [a-z] [a-z0-9\-_]* [^"\n] \p{L} \P{Lu}`+"\n", string(out))
+}
+
+func TestFormat_Escapes(t *testing.T) {
+	t.Parallel()
+
+	out, err := bnf.Format([]byte(`s ::= "\x41\r\n\0" %x0D.0A %x61-7A [\x00-\x1fé] "\xff"` + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, `s ::= "A\r\n\x00" "\r\n" [a-z] [\x00-\x1fé] "\xff"`+"\n", string(out))
+
+	g, err := bnf.LoadGrammarString(string(out))
+	assert.NoError(t, err)
+	ok, err := g.Match("A\r\n\x00\r\nqé\xff")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+}
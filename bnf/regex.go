@@ -14,7 +14,7 @@ func (r *Regex) match(ctx *context, pos int) ([]MatchResult, error) {
 	if loc != nil && loc[0] == 0 {
 		return []MatchResult{{
 			End:   pos + loc[1],
-			Nodes: []*ASTNode{{Type: "REGEX", Value: ctx.input[pos : pos+loc[1]]}},
+			Nodes: []*ASTNode{{Type: "REGEX", Value: ctx.input[pos : pos+loc[1]], Pos: pos, End: pos + loc[1]}},
 		}}, nil
 	}
 	return nil, nil
@@ -23,3 +23,10 @@ func (r *Regex) match(ctx *context, pos int) ([]MatchResult, error) {
 func (r *Regex) Expect() []string {
 	return []string{r.Re.String()}
 }
+
+// regexer is implemented by nodes matching the same text as a regex, which
+// the generator, enumerator and code generators use in their place.
+type regexer interface {
+	node
+	regex() *Regex
+}
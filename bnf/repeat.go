@@ -4,7 +4,8 @@ package bnf
 // A+  -> 1 or more repeats of A
 type repeat struct {
 	Node node
-	Min  int // 0=*, 1=+
+	Min  int  // 0=*, 1=+
+	Skip node // skipper matched between the iterations, in syntactic rules
 }
 
 func (r *repeat) match(ctx *context, pos int) ([]MatchResult, error) {
@@ -15,13 +16,20 @@ func (r *repeat) match(ctx *context, pos int) ([]MatchResult, error) {
 	for i := 0; ; i++ {
 		if i >= r.Min {
 			// Current state is valid, add to final results
-			finalResults = append(finalResults, currentResults...)
+			finalResults = append(finalResults, ctx.traced(currentResults, coverKey{node: r, branch: min(i, 2)})...)
 		}
 
 		var nextResults []MatchResult
 		for _, res := range currentResults {
+			start := res.End
+			if i > 0 && r.Skip != nil {
+				var err error
+				if start, err = skipEnd(ctx, r.Skip, start); err != nil {
+					return nil, err
+				}
+			}
 			// Try to match more
-			matches, err := ctx.Match(r.Node, res.End)
+			matches, err := ctx.Match(r.Node, start)
 			if err != nil {
 				return nil, err
 			}
@@ -36,6 +44,7 @@ func (r *repeat) match(ctx *context, pos int) ([]MatchResult, error) {
 					nextResults = append(nextResults, MatchResult{
 						End:   m.End,
 						Nodes: newNodes,
+						hits:  ctx.joinHits(res.hits, m.hits),
 					})
 				}
 			}
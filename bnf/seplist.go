@@ -0,0 +1,66 @@
+package bnf
+
+// dropped matches what Node matches but leaves its nodes out of the parse
+// tree, like the separators of a separated list.
+type dropped struct {
+	Node node
+}
+
+func (d *dropped) match(ctx *context, pos int) ([]MatchResult, error) {
+	matches, err := ctx.Match(d.Node, pos)
+	if err != nil {
+		return nil, err
+	}
+	// without nodes, matches ending at the same position are the same
+	seen := map[int]bool{}
+	var out []MatchResult
+	for _, m := range matches {
+		if !seen[m.End] {
+			seen[m.End] = true
+			out = append(out, MatchResult{End: m.End, hits: m.hits})
+		}
+	}
+	return out, nil
+}
+
+func (d *dropped) Expect() []string {
+	return d.Node.Expect()
+}
+
+// buildSepList builds X % S as X (S X)*, with S? after it for a trailing
+// separator and the whole optional for %*. The separators are dropped, so
+// the elements end up as a flat list of children.
+func buildSepList(l *SepListAST, rules map[string]*Rule) (node, error) {
+	n, err := buildNode(l.Node, rules)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := buildNode(l.Sep, rules)
+	if err != nil {
+		return nil, err
+	}
+	s := &dropped{Node: sep}
+	elems := []node{n, &repeat{Node: &sequence{Elements: []node{s, n}}}}
+	if l.Trailing {
+		elems = append(elems, &optional{Node: s})
+	}
+	var out node = &sequence{Elements: elems}
+	if l.Min == 0 {
+		out = &optional{Node: out}
+	}
+	return out, nil
+}
+
+// desugar writes l with the sequences and repetitions it stands for, the way
+// formats without separated lists express it.
+func (l *SepListAST) desugar() ExprAST {
+	elems := []ExprAST{l.Node, &RepeatAST{Node: &SeqAST{Elements: []ExprAST{l.Sep, l.Node}}, Min: 0, Max: -1}}
+	if l.Trailing {
+		elems = append(elems, &RepeatAST{Node: l.Sep, Min: 0, Max: 1})
+	}
+	var out ExprAST = &SeqAST{Elements: elems}
+	if l.Min == 0 {
+		out = &RepeatAST{Node: out, Min: 0, Max: 1}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package bnf_test
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSepList_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		match   []string
+		reject  []string
+	}{
+		{`<l> ::= [a-z] % ","`, []string{"a", "a,b,c"}, []string{"", "a,", ",a", "a,,b"}},
+		{`<l> ::= "[" [a-z] %* "," "]"`, []string{"[]", "[a]", "[a,b]"}, []string{"[,]", "[a,]"}},
+		{`<l> ::= [a-z] %% ";"`, []string{"a", "a;", "a;b;"}, []string{"", ";", "a;;"}},
+		{`<l> ::= "[" [a-z] %%* "," "]"`, []string{"[]", "[a,]", "[a,b]"}, []string{"[,]"}},
+		{`<l> ::= ([a-z] "=" [0-9]) % ("," | ";")`, []string{"a=1", "a=1;b=2,c=3"}, []string{"a=1,"}},
+		{`l ::= item %sep` + "\n" + `item ::= [a-z]` + "\n" + `sep ::= ","`, []string{"a", "a,b"}, []string{"a,"}},
+	}
+	for _, tt := range tests {
+		g, err := bnf.LoadGrammarString(tt.grammar)
+		if !assert.NoError(t, err, tt.grammar) {
+			continue
+		}
+		for _, in := range tt.match {
+			ok, err := g.Match(in)
+			assert.True(t, ok, "%s on %q", tt.grammar, in)
+			assert.NoError(t, err)
+		}
+		for _, in := range tt.reject {
+			ok, _ := g.Match(in)
+			assert.False(t, ok, "%s on %q", tt.grammar, in)
+		}
+	}
+}
+
+func TestSepList_Parse(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+<call> ::= <id> "(" <arg> %%* "," ")"
+<arg>  ::= <id> | <call>
+<id>   ::= [a-z]+
+`)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("f(a,g(b),c,)")
+	assert.NoError(t, err)
+	var types []string
+	for _, c := range tree.Children {
+		types = append(types, c.Type)
+	}
+	assert.Equal(t, []string{"id", "TERMINAL", "arg", "arg", "arg", "TERMINAL"}, types)
+	assert.Equal(t, ")", tree.Children[5].Value)
+}
+
+func TestSepList_Generate(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`<l> ::= "[" [a-c] %%* "," "]"`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		s, err := g.Generate(r, bnf.GenerateOptions{})
+		if !assert.NoError(t, err) {
+			break
+		}
+		ok, _ := g.Match(s)
+		assert.True(t, ok, "%q", s)
+	}
+
+	out, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"[]", "[a]", "[b]", "[c]", "[a,]", "[b,]", "[c,]"}, out)
+}
+
+func TestSepList_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `list ::= "[" item %%* "," "]" name - keyword % "." (a % b)*` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(`args ::= arg % "," | "(" arg %%* ";" ")"` + "\n" + `arg ::= "a"`))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: bnf.ExportW3C})
+	assert.NoError(t, err)
+	assert.Equal(t, `args ::= arg ("," arg)* | "(" (arg (";" arg)* ";"?)? ")"`, strings.Split(buf.String(), "\n")[0])
+}
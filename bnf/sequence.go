@@ -2,17 +2,25 @@ package bnf
 
 type sequence struct {
 	Elements []node
+	Skip     node // skipper matched between the elements, in syntactic rules
 }
 
 func (s *sequence) match(ctx *context, pos int) ([]MatchResult, error) {
 	// Start with one "empty" result at current position
 	currentResults := []MatchResult{{End: pos, Nodes: nil}}
 
-	for _, elem := range s.Elements {
+	for i, elem := range s.Elements {
 		var nextResults []MatchResult
 		for _, res := range currentResults {
+			start := res.End
+			if i > 0 && s.Skip != nil {
+				var err error
+				if start, err = skipEnd(ctx, s.Skip, start); err != nil {
+					return nil, err
+				}
+			}
 			// Try to match element at current result's end position
-			matches, err := ctx.Match(elem, res.End)
+			matches, err := ctx.Match(elem, start)
 			if err != nil {
 				return nil, err
 			}
@@ -25,6 +33,7 @@ func (s *sequence) match(ctx *context, pos int) ([]MatchResult, error) {
 				nextResults = append(nextResults, MatchResult{
 					End:   m.End,
 					Nodes: newNodes,
+					hits:  ctx.joinHits(res.hits, m.hits),
 				})
 			}
 		}
@@ -39,8 +48,11 @@ func (s *sequence) match(ctx *context, pos int) ([]MatchResult, error) {
 }
 
 func (s *sequence) Expect() []string {
-	if len(s.Elements) == 0 {
-		return nil
+	for _, e := range s.Elements {
+		// a lookahead consumes nothing, the element after it is expected
+		if _, ok := e.(*notPredicate); !ok {
+			return e.Expect()
+		}
 	}
-	return s.Elements[0].Expect()
+	return nil
 }
@@ -0,0 +1,122 @@
+package bnf
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// skipper matches Node as often as it can, taking the longest match each
+// time, and so has a single result. It is the @skip expression of a grammar,
+// matched between the elements of its syntactic rules. Failures of Node are not
+// recorded, they would only clutter parse errors.
+type skipper struct {
+	Node node
+}
+
+func (s *skipper) match(ctx *context, pos int) ([]MatchResult, error) {
+	res := MatchResult{End: pos}
+	for {
+		matches, err := ctx.lookahead(s.Node, res.End)
+		if err != nil {
+			return nil, err
+		}
+		longest := -1
+		for i, m := range matches {
+			if m.End > res.End && (longest < 0 || m.End > matches[longest].End) {
+				longest = i
+			}
+		}
+		if longest < 0 {
+			return []MatchResult{res}, nil
+		}
+		res = MatchResult{End: matches[longest].End, hits: ctx.joinHits(res.hits, matches[longest].hits)}
+	}
+}
+
+func (s *skipper) Expect() []string {
+	return s.Node.Expect()
+}
+
+// skipEnd returns the position after the text skip skips at pos.
+func skipEnd(ctx *context, skip node, pos int) (int, error) {
+	matches, err := ctx.Match(skip, pos)
+	if err != nil || len(matches) == 0 {
+		return pos, err
+	}
+	return matches[0].End, nil
+}
+
+// isLexical tells whether the rule is lexical by its name: its first letter,
+// after any import alias, is uppercase like in NUMBER or Ident.
+func isLexical(name string) bool {
+	name = name[strings.LastIndex(name, ".")+1:]
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// lexicalRules returns the rules matching their text contiguously: those with
+// lexical names or the @lexical or @token annotation, those used by @skip and
+// all rules used by lexical ones.
+func lexicalRules(ast *GrammarAST) map[string]bool {
+	exprs := map[string]ExprAST{}
+	var queue []string
+	for _, r := range ast.Rules {
+		exprs[r.Name] = r.Expr
+		if isLexical(r.Name) || slices.Contains(r.Annotations, "lexical") || slices.Contains(r.Annotations, "token") {
+			queue = append(queue, r.Name)
+		}
+	}
+	refs := func(e ExprAST) {
+		mapIdents(e, func(name string) string {
+			queue = append(queue, name)
+			return name
+		})
+	}
+	if ast.Skip != nil {
+		refs(ast.Skip.Expr)
+	}
+
+	lexical := map[string]bool{}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if lexical[name] {
+			continue
+		}
+		lexical[name] = true
+		if e, ok := exprs[name]; ok {
+			refs(e)
+		}
+	}
+	return lexical
+}
+
+// setSkip makes the sequences and repetitions of n skip between their
+// elements. It stops at references, every rule decides on its own.
+func setSkip(n node, skip node) {
+	switch t := n.(type) {
+	case *sequence:
+		t.Skip = skip
+		for _, e := range t.Elements {
+			setSkip(e, skip)
+		}
+	case *repeat:
+		t.Skip = skip
+		setSkip(t.Node, skip)
+	case *choice:
+		for _, o := range t.Options {
+			setSkip(o, skip)
+		}
+	case *optional:
+		setSkip(t.Node, skip)
+	case *notPredicate:
+		setSkip(t.Node, skip)
+	case *difference:
+		setSkip(t.Node, skip)
+		setSkip(t.Except, skip)
+	case *dropped:
+		setSkip(t.Node, skip)
+	}
+}
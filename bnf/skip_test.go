@@ -0,0 +1,148 @@
+package bnf_test
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const skipGrammar = `
+@skip ::= /[ \t\r\n]+/ | comment
+
+program  ::= stmt*
+stmt     ::= "let" NAME "=" expr ";"
+expr     ::= number % "+"
+@lexical
+number   ::= digit+ ("." digit+)?
+digit    ::= [0-9]
+NAME     ::= [a-z] [a-z0-9]*
+comment  ::= "#" (!"\n" .)*
+`
+
+func TestSkip_Match(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(skipGrammar)
+	assert.NoError(t, err)
+
+	for _, in := range []string{
+		"let x = 1;",
+		"  let x=1+2 ;\n\n",
+		"let x = 1 # one\n + 2.5;\tlet y2=3;",
+		"# only a comment",
+		"",
+	} {
+		ok, err := g.Match(in)
+		assert.True(t, ok, "%q", in)
+		assert.NoError(t, err)
+	}
+	for _, in := range []string{
+		"let x = 1 2;", // number is lexical
+		"let x = 1. 5;",
+		"let x y = 1;", // so is NAME
+		"let x = 1; # comment\n let",
+	} {
+		ok, _ := g.Match(in)
+		assert.False(t, ok, "%q", in)
+	}
+}
+
+func TestSkip_Parse(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(skipGrammar)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("let ab = 1 + # two\n 23 ;")
+	assert.NoError(t, err)
+	stmt := tree.Children[0]
+	assert.Equal(t, "stmt", stmt.Type)
+	assert.Equal(t, []string{"TERMINAL", "NAME", "TERMINAL", "expr", "TERMINAL"}, childTypes(stmt))
+	assert.Equal(t, []string{"number", "number"}, childTypes(stmt.Children[3]))
+
+	_, err = g.Parse("let x = 1 2;")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 10, perr.Pos)
+		assert.Equal(t, []string{`"+"`, `";"`}, perr.Expected)
+	}
+}
+
+func TestSkip_Generate(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(skipGrammar)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		s, err := g.Generate(r, bnf.GenerateOptions{MaxDepth: 6})
+		if !assert.NoError(t, err) {
+			break
+		}
+		ok, _ := g.Match(s)
+		assert.True(t, ok, "%q", s)
+	}
+
+	g, err = bnf.LoadGrammarString("@skip ::= \" \"\n" + `s ::= "a" "b"*`)
+	assert.NoError(t, err)
+	out, err := g.Enumerate(bnf.EnumerateOptions{MaxLen: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "a ", "ab", "a b", "abb"}, out)
+}
+
+func TestSkip_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grammar string
+		err     string
+	}{
+		{"@skip ::= \" \"\n@skip ::= \"\\t\"\ns ::= \"a\"", "duplicate @skip directive"},
+		{"@skip \" \"\ns ::= \"a\"", "@skip expects ::= followed by an expression"},
+		{"@lexical\n", "expected a rule name"},
+	}
+	for _, tt := range tests {
+		_, err := bnf.LoadGrammarString(tt.grammar)
+		assert.ErrorContains(t, err, tt.err, tt.grammar)
+	}
+
+	g, err := bnf.LoadGrammarString("@skip ::= ws\ns ::= \"a\"")
+	assert.NoError(t, err)
+	assert.EqualError(t, g.ValidateGrammar(), "undefined rule: ws")
+}
+
+func TestSkip_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `@skip            ::= /\s+/ | comment` + "\n" +
+		`list             ::= item*` + "\n" +
+		`@lexical item    ::= [a-z]+` + "\n" +
+		`@lexical comment ::= "#" (!"\n" .)*` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(src))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	warnings, err := bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: bnf.ExportW3C})
+	assert.NoError(t, err)
+	assert.Contains(t, warnings, "@skip cannot be expressed in W3C EBNF, the exported grammar allows no text between elements")
+}
+
+func childTypes(n *bnf.ASTNode) []string {
+	var types []string
+	for _, c := range n.Children {
+		types = append(types, c.Type)
+	}
+	return types
+}
@@ -0,0 +1,200 @@
+package bnf
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stdImport is the import path of the standard rule library.
+const stdImport = "std"
+
+// builtin is a rule of the standard library matched by hand-written Go code
+// instead of a tree of nodes. It matches the same text as its pattern, which
+// the generator, enumerator and code generators use in its place.
+type builtin struct {
+	Name string
+	scan func(s string) int // length of the match at the start of s, or -1
+	re   *Regex
+}
+
+// stdRules are the rules defined by `@import "std"`, in definition order.
+var stdRules = []*builtin{
+	{Name: "DIGIT", scan: scanRune(isDigit), re: stdPattern(`[0-9]`)},
+	{Name: "ALPHA", scan: scanRune(isASCIILetter), re: stdPattern(`[A-Za-z]`)},
+	{Name: "HEXDIG", scan: scanRune(isHexDigit), re: stdPattern(`[0-9A-Fa-f]`)},
+	{Name: "WS", scan: scanWS, re: stdPattern(`[ \t]+`)},
+	{Name: "NEWLINE", scan: scanNewline, re: stdPattern(`\r\n|\n|\r`)},
+	{Name: "IDENT", scan: scanIdent, re: stdPattern(`[A-Za-z_][A-Za-z0-9_]*`)},
+	{Name: "INTEGER", scan: scanInteger, re: stdPattern(`[-+]?[0-9]+`)},
+	{Name: "FLOAT", scan: scanFloat, re: stdPattern(`[-+]?(?:(?:[0-9]+\.[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?|[0-9]+[eE][-+]?[0-9]+)`)},
+	{Name: "QUOTED_STRING", scan: scanQuoted, re: stdPattern(`"(?:[^"\\\n]|\\.)*"`)},
+	{Name: "UNICODE_LETTER", scan: scanRune(unicode.IsLetter), re: stdPattern(`\p{L}`)},
+}
+
+func stdPattern(pattern string) *Regex {
+	return &Regex{Re: regexp.MustCompile(pattern)}
+}
+
+// stdGrammar returns the AST of the standard library.
+func stdGrammar() *GrammarAST {
+	ast := &GrammarAST{}
+	for _, b := range stdRules {
+		ast.Rules = append(ast.Rules, &RuleAST{Name: b.Name, Expr: &BuiltinAST{Name: b.Name}})
+	}
+	return ast
+}
+
+func lookupBuiltin(name string) *builtin {
+	for _, b := range stdRules {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+func (b *builtin) match(ctx *context, pos int) ([]MatchResult, error) {
+	n := b.scan(ctx.input[pos:])
+	if n < 0 {
+		return nil, nil
+	}
+	return []MatchResult{{
+		End:   pos + n,
+		Nodes: []*ASTNode{{Type: "REGEX", Value: ctx.input[pos : pos+n], Pos: pos, End: pos + n}},
+	}}, nil
+}
+
+func (b *builtin) Expect() []string {
+	return []string{b.Name}
+}
+
+func (b *builtin) regex() *Regex {
+	return b.re
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+func scanRune(ok func(rune) bool) func(string) int {
+	return func(s string) int {
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || !ok(r) {
+			return -1
+		}
+		return size
+	}
+}
+
+// digits returns the number of leading ASCII digits of s.
+func digits(s string) int {
+	i := 0
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	return i
+}
+
+// sign returns 1 when s starts with + or -.
+func sign(s string) int {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		return 1
+	}
+	return 0
+}
+
+func scanWS(s string) int {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	if i == 0 {
+		return -1
+	}
+	return i
+}
+
+func scanNewline(s string) int {
+	switch {
+	case len(s) >= 2 && s[0] == '\r' && s[1] == '\n':
+		return 2
+	case s != "" && (s[0] == '\n' || s[0] == '\r'):
+		return 1
+	}
+	return -1
+}
+
+func scanIdent(s string) int {
+	if s == "" || !isIdentStart(rune(s[0])) {
+		return -1
+	}
+	i := 1
+	for i < len(s) && (s[i] == '_' || isASCIILetter(rune(s[i])) || isDigit(rune(s[i]))) {
+		i++
+	}
+	return i
+}
+
+func scanInteger(s string) int {
+	i := sign(s)
+	n := digits(s[i:])
+	if n == 0 {
+		return -1
+	}
+	return i + n
+}
+
+func scanFloat(s string) int {
+	i := sign(s)
+	whole := digits(s[i:])
+	i += whole
+	fraction := false
+	if i < len(s) && s[i] == '.' {
+		n := digits(s[i+1:])
+		if whole == 0 && n == 0 {
+			return -1
+		}
+		i += 1 + n
+		fraction = true
+	} else if whole == 0 {
+		return -1
+	}
+	// exponent
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		j += sign(s[j:])
+		if n := digits(s[j:]); n > 0 {
+			return j + n
+		}
+	}
+	if !fraction {
+		return -1
+	}
+	return i
+}
+
+func scanQuoted(s string) int {
+	if s == "" || s[0] != '"' {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i + 1
+		case '\n':
+			return -1
+		case '\\':
+			if i+1 >= len(s) || s[i+1] == '\n' {
+				return -1
+			}
+			_, size := utf8.DecodeRuneInString(s[i+1:])
+			i += size
+		}
+	}
+	return -1
+}
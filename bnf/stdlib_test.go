@@ -0,0 +1,61 @@
+package bnf
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdRules_MatchTheirPatterns(t *testing.T) {
+	t.Parallel()
+
+	inputs := []string{
+		"", "0", "7x", "a", "Z9", "f", "G", "_", "ż", "Ωmega", " ", " \t x", "\t", "\n", "\r\n", "\r", "\n\r",
+		"abc_12 x", "_x", "9ab", "+12", "-0", "12.", "-", "+", ".", ".5", "1.5", "-1.5e10", "1e5", "1e", "1e+",
+		"1.e3", ".e3", "1.5E-3x", "12e5e5", `""`, `"a"b`, `"a\"b"`, `"a\\"`, `"a\`, `"a\nb"`, "\"a\nb\"", `"\ż"`,
+		`"unterminated`, "\xff",
+	}
+	for _, b := range stdRules {
+		for _, in := range inputs {
+			want := -1
+			if loc := b.re.Re.FindStringIndex(in); loc != nil && loc[0] == 0 {
+				want = loc[1]
+			}
+			assert.Equal(t, want, b.scan(in), "%s on %q", b.Name, in)
+		}
+	}
+}
+
+func TestStdRules_Grammar(t *testing.T) {
+	t.Parallel()
+
+	g, err := LoadGrammarString(`@import "std"
+assignment ::= IDENT WS? "=" WS? value
+value      ::= FLOAT | INTEGER | QUOTED_STRING
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"assignment", "value", "WS", "IDENT", "INTEGER", "FLOAT", "QUOTED_STRING"}, g.ruleNames())
+
+	for _, in := range []string{"x=1", "x = -1.5e3", `name= "a \"b\""`} {
+		ok, err := g.Match(in)
+		assert.True(t, ok, in)
+		assert.NoError(t, err, in)
+	}
+
+	_, err = g.Parse("x = 1.")
+	assert.NoError(t, err)
+	_, err = g.Parse("x = ")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "FLOAT")
+	}
+
+	// generated sentences are accepted
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		s, err := g.Generate(r, GenerateOptions{})
+		assert.NoError(t, err)
+		ok, _ := g.Match(s)
+		assert.True(t, ok, s)
+	}
+}
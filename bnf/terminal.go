@@ -1,6 +1,10 @@
 package bnf
 
-import "fmt"
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
 
 type terminal struct {
 	Value string
@@ -14,6 +18,8 @@ func (t *terminal) match(ctx *context, pos int) ([]MatchResult, error) {
 		node := &ASTNode{
 			Type:  "TERMINAL",
 			Value: t.Value,
+			Pos:   pos,
+			End:   pos + len(t.Value),
 		}
 		return []MatchResult{{
 			End:   pos + len(t.Value),
@@ -26,3 +32,46 @@ func (t *terminal) match(ctx *context, pos int) ([]MatchResult, error) {
 func (t *terminal) Expect() []string {
 	return []string{fmt.Sprintf("%q", t.Value)}
 }
+
+// caselessTerminal matches a literal ignoring case, comparing runes with
+// Unicode simple case folding like strings.EqualFold.
+type caselessTerminal struct {
+	Value string
+}
+
+func (t *caselessTerminal) match(ctx *context, pos int) ([]MatchResult, error) {
+	end := pos
+	for _, want := range t.Value {
+		got, size := utf8.DecodeRuneInString(ctx.input[end:])
+		if size == 0 || !equalFoldRune(want, got) {
+			return nil, nil
+		}
+		end += size
+	}
+	node := &ASTNode{
+		Type:  "TERMINAL",
+		Value: ctx.input[pos:end], // the text as written in the input
+		Pos:   pos,
+		End:   end,
+	}
+	return []MatchResult{{
+		End:   end,
+		Nodes: []*ASTNode{node},
+	}}, nil
+}
+
+func (t *caselessTerminal) Expect() []string {
+	return []string{fmt.Sprintf("%qi", t.Value)}
+}
+
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
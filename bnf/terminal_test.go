@@ -16,3 +16,19 @@ func TestTerminal(t *testing.T) {
 	assert.Nil(t, testMatch(n, "ab", 0))                 // not matching
 	assert.Nil(t, testMatch(n, "", 0))                   // not matching
 }
+
+func TestCaselessTerminal(t *testing.T) {
+	t.Parallel()
+
+	n := &caselessTerminal{Value: "select"}
+	assert.Equal(t, []int{6}, testMatch(n, "select", 0))
+	assert.Equal(t, []int{6}, testMatch(n, "SeLeCT x", 0))
+	assert.Equal(t, []int{7}, testMatch(n, "xSELECT", 1))
+	assert.Nil(t, testMatch(n, "selec", 0))
+	assert.Nil(t, testMatch(n, "selekt", 0))
+
+	// Unicode simple folding, the matched text may differ in length
+	assert.Equal(t, []int{len("ΣΊΣΥΦΟΣ")}, testMatch(&caselessTerminal{Value: "σίσυφος"}, "ΣΊΣΥΦΟΣ", 0))
+	assert.Equal(t, []int{3}, testMatch(&caselessTerminal{Value: "k"}, "K", 0)) // Kelvin sign
+	assert.Equal(t, []string{`"select"i`}, n.Expect())
+}
@@ -0,0 +1,260 @@
+package bnf
+
+import (
+	"fmt"
+	"slices"
+)
+
+// scanner splits the input into tokens where syntactic rules of grammars with
+// @token definitions look for one. It takes the longest token, preferring
+// literals and then earlier definitions on a tie, so that keywords are told
+// apart from identifiers once per position.
+type scanner struct {
+	skip node       // skipper of @skip between tokens, nil without one
+	defs []tokenDef // in priority order
+}
+
+// tokenDef is a kind of token: a literal of a syntactic rule or a @token rule.
+type tokenDef struct {
+	kind string // name of the rule, or the literal as written, like "let"
+	node node   // what the token matches in the input
+	rule bool   // the token is a @token rule, a leaf named after it
+}
+
+// scanned is a token of the input.
+type scanned struct {
+	def      *tokenDef // nil when no token matches
+	pos, end int
+	hits     *trace // derivation trace of the token, when the context traces coverage
+}
+
+// expect returns what the token is called in parse errors.
+func (d *tokenDef) expect() []string {
+	if d.rule {
+		return []string{d.kind}
+	}
+	return d.node.Expect()
+}
+
+// leaf returns the node of the token in parse trees: a terminal for
+// literals and a node named after the rule for @token rules.
+func (tok scanned) leaf(input string) *ASTNode {
+	leaf := &ASTNode{Type: "TERMINAL", Value: input[tok.pos:tok.end], Pos: tok.pos, End: tok.end}
+	if tok.def.rule {
+		leaf.Type = tok.def.kind
+	}
+	return leaf
+}
+
+// at returns the token following pos with nothing but skipped text between.
+// Tokens are scanned on demand rather than all upfront, so rules matching text
+// can be mixed with those matching tokens.
+func (sc *scanner) at(ctx *context, pos int) (scanned, error) {
+	byPos := ctx.tokens[sc]
+	if tok, ok := byPos[pos]; ok {
+		return tok, nil
+	}
+	start, err := sc.skipEnd(ctx, pos)
+	if err != nil {
+		return scanned{}, err
+	}
+	tok, err := sc.next(ctx, start)
+	if err != nil {
+		return scanned{}, err
+	}
+	if byPos == nil {
+		if ctx.tokens == nil {
+			ctx.tokens = map[*scanner]map[int]scanned{}
+		}
+		byPos = map[int]scanned{}
+		ctx.tokens[sc] = byPos
+	}
+	byPos[pos] = tok
+	return tok, nil
+}
+
+func (sc *scanner) skipEnd(ctx *context, pos int) (int, error) {
+	if sc.skip == nil {
+		return pos, nil
+	}
+	return skipEnd(ctx, sc.skip, pos)
+}
+
+// next returns the token starting at pos.
+func (sc *scanner) next(ctx *context, pos int) (scanned, error) {
+	best := scanned{pos: pos, end: pos}
+	for i := range sc.defs {
+		def := &sc.defs[i]
+		matches, err := ctx.lookahead(def.node, pos)
+		if err != nil {
+			return scanned{}, err
+		}
+		for _, m := range matches {
+			if m.End > best.end {
+				best = scanned{def: def, pos: pos, end: m.End, hits: m.hits}
+			}
+		}
+	}
+	return best, nil
+}
+
+// token matches a single token of the kind of def, in syntactic rules of
+// grammars with @token definitions.
+type token struct {
+	def     *tokenDef
+	scanner *scanner
+}
+
+func (t *token) match(ctx *context, pos int) ([]MatchResult, error) {
+	tok, err := t.scanner.at(ctx, pos)
+	if err != nil || tok.def != t.def {
+		return nil, err
+	}
+	// the trace of @token rules starts with the rule, matched through a nonTerminal
+	return []MatchResult{{End: tok.end, Nodes: []*ASTNode{tok.leaf(ctx.input)}, hits: tok.hits}}, nil
+}
+
+func (t *token) Expect() []string {
+	return t.def.expect()
+}
+
+// Tokens splits the input into the tokens of a grammar with @token
+// definitions, the leaves syntactic rules match. It fails with a *ParseError
+// at the first text, other than what @skip skips, no token matches.
+func (g *Grammar) Tokens(input string) ([]*ASTNode, error) {
+	if g.scanner == nil {
+		return nil, fmt.Errorf("grammar has no @token definitions")
+	}
+	ctx := NewContext(input)
+	var out []*ASTNode
+	end := 0
+	for {
+		tok, err := g.scanner.at(ctx, end)
+		if err != nil {
+			return nil, err
+		}
+		if tok.def == nil {
+			break
+		}
+		out = append(out, tok.leaf(input))
+		end = tok.end
+	}
+	end, err := g.scanner.skipEnd(ctx, end)
+	if err != nil || end == len(input) {
+		return out, err
+	}
+
+	var expected []string
+	for i := range g.scanner.defs {
+		expected = mergeExpected(expected, g.scanner.defs[i].expect())
+	}
+	line, col := lineCol(input, end)
+	return out, &ParseError{
+		Pos:      end,
+		Line:     line,
+		Column:   col,
+		Expected: expected,
+		Found:    ctx.foundAt(end),
+		Width:    expectedWidth(expected),
+	}
+}
+
+// newScanner creates the scanner of the @token rules of ast and the literals
+// of its syntactic rules, and makes those rules match tokens instead of text.
+func newScanner(ast *GrammarAST, rules map[string]*Rule, order []string, lexical map[string]bool, skip node) *scanner {
+	sc := &scanner{skip: skip}
+	seen := map[string]bool{}
+	var tokens []tokenDef
+	for _, r := range ast.Rules {
+		if slices.Contains(r.Annotations, "token") && !slices.ContainsFunc(tokens, func(d tokenDef) bool { return d.kind == r.Name }) {
+			tokens = append(tokens, tokenDef{kind: r.Name, node: &nonTerminal{Name: r.Name, Rule: rules[r.Name]}, rule: true})
+		}
+	}
+
+	// first collect the literals, which come first in priority order
+	var collect func(n node)
+	collect = func(n node) {
+		switch t := n.(type) {
+		case *terminal, *caselessTerminal:
+			if kind := formatNode(n); !seen[kind] && kind != `""` {
+				seen[kind] = true
+				sc.defs = append(sc.defs, tokenDef{kind: kind, node: n})
+			}
+		default:
+			for _, c := range children(t) {
+				collect(c)
+			}
+		}
+	}
+	for _, name := range order {
+		if !lexical[name] {
+			collect(rules[name].Expr)
+		}
+	}
+	sc.defs = append(sc.defs, tokens...)
+
+	byKind := map[string]*tokenDef{}
+	for i := range sc.defs {
+		byKind[sc.defs[i].kind] = &sc.defs[i]
+	}
+	var replace func(n node) node
+	replace = func(n node) node {
+		switch t := n.(type) {
+		case *terminal, *caselessTerminal:
+			if def, ok := byKind[formatNode(n)]; ok && !def.rule {
+				return &token{def: def, scanner: sc}
+			}
+		case *nonTerminal:
+			if def, ok := byKind[t.Name]; ok && def.rule {
+				return &token{def: def, scanner: sc}
+			}
+		case *sequence:
+			for i, e := range t.Elements {
+				t.Elements[i] = replace(e)
+			}
+		case *choice:
+			for i, o := range t.Options {
+				t.Options[i] = replace(o)
+			}
+		case *repeat:
+			t.Node = replace(t.Node)
+		case *optional:
+			t.Node = replace(t.Node)
+		case *notPredicate:
+			t.Node = replace(t.Node)
+		case *difference:
+			t.Node = replace(t.Node)
+			t.Except = replace(t.Except)
+		case *dropped:
+			t.Node = replace(t.Node)
+		}
+		return n
+	}
+	for _, name := range order {
+		if !lexical[name] {
+			rules[name].Expr = replace(rules[name].Expr)
+		}
+	}
+	return sc
+}
+
+// children returns the nodes directly within n.
+func children(n node) []node {
+	switch t := n.(type) {
+	case *sequence:
+		return t.Elements
+	case *choice:
+		return t.Options
+	case *repeat:
+		return []node{t.Node}
+	case *optional:
+		return []node{t.Node}
+	case *notPredicate:
+		return []node{t.Node}
+	case *difference:
+		return []node{t.Node, t.Except}
+	case *dropped:
+		return []node{t.Node}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package bnf_test
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tokenGrammar = `
+@skip ::= /[ \t\r\n]+/
+
+program ::= stmt*
+stmt    ::= "let" IDENT "=" expr ";" | "if" expr ("==" | "=") expr ";"
+expr    ::= IDENT | NUMBER
+
+@token IDENT  ::= [a-z] [a-z0-9]*
+@token NUMBER ::= /[0-9]+/
+`
+
+func TestToken_Match(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(tokenGrammar)
+	assert.NoError(t, err)
+
+	for _, in := range []string{
+		"let letter = 1;",
+		"let iffy=x; if iffy == 2;",
+		"if a=b;",
+		"",
+	} {
+		ok, err := g.Match(in)
+		assert.True(t, ok, "%q", in)
+		assert.NoError(t, err)
+	}
+	for _, in := range []string{
+		"let let = 1;", // keywords are no identifiers
+		"letx = 1;",    // nor part of them
+		"if a = = b;",
+		"let x = 1 ?",
+	} {
+		ok, _ := g.Match(in)
+		assert.False(t, ok, "%q", in)
+	}
+}
+
+func TestToken_Parse(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(tokenGrammar)
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("if ab == 12;")
+	assert.NoError(t, err)
+	stmt := tree.Children[0]
+	assert.Equal(t, []string{"TERMINAL", "expr", "TERMINAL", "expr", "TERMINAL"}, childTypes(stmt))
+	assert.Equal(t, "==", stmt.Children[2].Value)
+
+	id := stmt.Children[1].Children[0]
+	assert.Equal(t, &bnf.ASTNode{Type: "IDENT", Value: "ab", Pos: 3, End: 5}, id)
+
+	_, err = g.Parse("let let = 1;")
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 4, perr.Pos)
+		assert.Equal(t, []string{"IDENT"}, perr.Expected)
+	}
+}
+
+func TestToken_Tokens(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(tokenGrammar)
+	assert.NoError(t, err)
+
+	toks, err := g.Tokens("if x==10;")
+	assert.NoError(t, err)
+	var got []string
+	for _, tok := range toks {
+		got = append(got, tok.Type+" "+tok.Value)
+	}
+	assert.Equal(t, []string{"TERMINAL if", "IDENT x", "TERMINAL ==", "NUMBER 10", "TERMINAL ;"}, got)
+
+	toks, err = g.Tokens("let x ? 1")
+	assert.Len(t, toks, 2)
+	var perr *bnf.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 6, perr.Pos)
+		assert.Contains(t, perr.Expected, "NUMBER")
+	}
+
+	g, err = bnf.LoadGrammarString(`s ::= "a"`)
+	assert.NoError(t, err)
+	_, err = g.Tokens("a")
+	assert.EqualError(t, err, "grammar has no @token definitions")
+}
+
+func TestToken_Priority(t *testing.T) {
+	t.Parallel()
+
+	// on a tie the earlier definition wins, literals before all of them
+	g, err := bnf.LoadGrammarString(`
+s ::= (HEX | DEC | "ff")*
+@token DEC ::= /[0-9]+/
+@token HEX ::= /[0-9a-f]+/
+`)
+	assert.NoError(t, err)
+	toks, err := g.Tokens("12ff1ff")
+	assert.NoError(t, err)
+	var got []string
+	for _, tok := range toks {
+		got = append(got, tok.Type+" "+tok.Value)
+	}
+	assert.Equal(t, []string{"HEX 12ff1ff"}, got)
+
+	toks, err = g.Tokens("12")
+	assert.NoError(t, err)
+	assert.Equal(t, "DEC", toks[0].Type)
+
+	toks, err = g.Tokens("ff")
+	assert.NoError(t, err)
+	assert.Equal(t, "TERMINAL", toks[0].Type)
+}
+
+func TestToken_Generate(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(tokenGrammar)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		s, err := g.Generate(r, bnf.GenerateOptions{MaxDepth: 6})
+		if !assert.NoError(t, err) {
+			break
+		}
+		ok, _ := g.Match(s)
+		assert.True(t, ok, "%q", s)
+	}
+}
+
+func TestToken_GenerateApart(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+		s        ::= "let" ID ID
+		@token ID ::= /[a-z]+/
+		@skip    ::= " "*
+	`)
+	assert.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 300 {
+		s, err := g.Generate(r, bnf.GenerateOptions{})
+		if !assert.NoError(t, err) {
+			break
+		}
+		ok, _ := g.Match(s)
+		assert.True(t, ok, "%q", s)
+	}
+
+	// without @skip the identifier always runs into the keyword
+	g, err = bnf.LoadGrammarString(`
+		s        ::= "let" ID
+		@token ID ::= /[a-z]+/
+	`)
+	assert.NoError(t, err)
+	_, err = g.Generate(r, bnf.GenerateOptions{})
+	assert.EqualError(t, err, "cannot generate text scanned as ID")
+}
+
+func TestToken_Coverage(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(tokenGrammar)
+	assert.NoError(t, err)
+
+	cases, _, err := g.CoverageCorpus()
+	assert.NoError(t, err)
+
+	// the structure of @token rules is covered through the scanner
+	cov := bnf.NewCoverage(g)
+	for _, c := range cases {
+		ok, err := cov.Match(c.Input)
+		assert.True(t, ok, c.Input)
+		assert.NoError(t, err)
+	}
+	for _, p := range cov.Report() {
+		assert.NotZero(t, p.Hits, p.String())
+	}
+}
+
+func TestToken_FormatAndExport(t *testing.T) {
+	t.Parallel()
+
+	src := `list        ::= "(" NAME* ")"` + "\n" +
+		`@token NAME ::= /[a-z]+/` + "\n"
+	out, err := bnf.Format([]byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+
+	p, err := bnf.NewParser(strings.NewReader(src))
+	assert.NoError(t, err)
+	ast, err := p.ParseGrammar()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	warnings, err := bnf.ExportGrammar(&buf, ast, bnf.ExportOptions{Format: bnf.ExportW3C})
+	assert.NoError(t, err)
+	assert.Contains(t, warnings, "@token rules are exported as plain rules, the exported grammar neither scans tokens first nor reserves keywords")
+}
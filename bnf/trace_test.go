@@ -0,0 +1,26 @@
+package bnf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace_Shared(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext("")
+	ctx.trace = true
+	a := ctx.traced([]MatchResult{{}}, coverKey{rule: "a"})[0].hits
+	b := ctx.traced([]MatchResult{{hits: a}}, coverKey{rule: "b"})[0].hits
+	ab := ctx.joinHits(b, ctx.traced([]MatchResult{{}}, coverKey{rule: "c"})[0].hits)
+
+	// extending a trace keeps the trace it extends
+	assert.Same(t, a, b.rest)
+	assert.Same(t, b, ab.join)
+
+	var keys []string
+	ab.each(func(k coverKey) { keys = append(keys, k.rule) })
+	assert.Equal(t, []string{"b", "a", "c"}, keys)
+	assert.Same(t, a, ctx.joinHits(nil, a))
+}
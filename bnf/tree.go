@@ -10,6 +10,8 @@ type ASTNode struct {
 	Type     string     // Rule name, "TERMINAL", or "REGEX"
 	Value    string     // The actual text matched
 	Children []*ASTNode // Child nodes
+	Pos      int        // Byte offset where the match starts
+	End      int        // Byte offset where the match ends
 }
 
 // String returns a multi-line, indented string representation of the parse tree.
@@ -19,6 +21,19 @@ func (n *ASTNode) String() string {
 	return sb.String()
 }
 
+// Text returns the values of the leaves of the node joined: the input matched
+// by the node without the text skipped by @skip or dropped from the tree.
+func (n *ASTNode) Text() string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	var sb strings.Builder
+	for _, child := range n.Children {
+		sb.WriteString(child.Text())
+	}
+	return sb.String()
+}
+
 func (n *ASTNode) format(sb *strings.Builder, level int) {
 	indent := strings.Repeat("  ", level)
 
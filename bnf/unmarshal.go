@@ -0,0 +1,200 @@
+package bnf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalError describes a parse tree that does not fit the Go value it is unmarshaled into.
+type UnmarshalError struct {
+	Pos   int    // byte offset of the offending node
+	Rule  string // rule of the offending node
+	Field string // Go field path, e.g. "Time.Minute"
+	Msg   string
+}
+
+// Error returns a description of the mismatch.
+func (err *UnmarshalError) Error() string {
+	where := ""
+	if err.Field != "" {
+		where = " into " + err.Field
+	}
+	return fmt.Sprintf("bnf: cannot unmarshal <%s>%s at offset %d: %s", err.Rule, where, err.Pos, err.Msg)
+}
+
+var (
+	astNodeType         = reflect.TypeFor[*ASTNode]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Unmarshal fills the value pointed to by v from a parse tree, similar to how
+// encoding/json fills values from JSON:
+//
+//   - strings and byte slices receive the matched text, numbers and booleans its parsed form
+//     and encoding.TextUnmarshaler implementations are handed the text;
+//   - a *ASTNode receives the node itself;
+//   - struct fields tagged `bnf:"rule"` are filled from the nodes of that rule
+//     found below the struct's node, not looking into the found nodes;
+//   - slice fields take every such node, other fields exactly one unless the
+//     tag carries the "optional" flag as in `bnf:"minute,optional"`.
+//
+// Fields without a tag are left alone. Mismatches are reported as *UnmarshalError.
+// The text of a node is the values of its leaves joined, see Grammar.Unmarshal
+// for the text of the input instead.
+func Unmarshal(tree *ASTNode, v any) error {
+	return unmarshal(tree, v, (*ASTNode).Text)
+}
+
+// Unmarshal parses the input and fills the value pointed to by v from the parse
+// tree like the package-level Unmarshal. The text of a node is the input it
+// matched, including what @skip skipped and what - dropped from the tree.
+func (g *Grammar) Unmarshal(input string, v any) error {
+	tree, err := g.Parse(input)
+	if err != nil {
+		return err
+	}
+	return unmarshal(tree, v, func(n *ASTNode) string { return input[n.Pos:n.End] })
+}
+
+func unmarshal(tree *ASTNode, v any, text func(*ASTNode) string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("bnf: Unmarshal needs a non-nil pointer, got %T", v)
+	}
+	if tree == nil {
+		return fmt.Errorf("bnf: Unmarshal of a nil tree")
+	}
+	return unmarshalNode(tree, rv.Elem(), rv.Elem().Type().Name(), text)
+}
+
+func unmarshalNode(n *ASTNode, v reflect.Value, path string, text func(*ASTNode) string) error {
+	fail := func(format string, args ...any) error {
+		return &UnmarshalError{Pos: n.Pos, Rule: n.Type, Field: path, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	if v.Type() == astNodeType {
+		v.Set(reflect.ValueOf(n))
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalNode(n, v.Elem(), path, text)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text(n))); err != nil {
+			return fail("%v", err)
+		}
+		return nil
+	}
+
+	s := text(n)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fail("%q is not a valid %s", s, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fail("%q is not a valid %s", s, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fail("%q is not a valid %s", s, v.Type())
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fail("%q is not a valid %s", s, v.Type())
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fail("unsupported type %s", v.Type())
+		}
+		v.SetBytes([]byte(s))
+	case reflect.Struct:
+		return unmarshalStruct(n, v, path, text)
+	default:
+		return fail("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func unmarshalStruct(n *ASTNode, v reflect.Value, path string, text func(*ASTNode) string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("bnf")
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+		fieldPath := path + "." + f.Name
+		rule, flags, _ := strings.Cut(tag, ",")
+		optional := false
+		for _, flag := range strings.Split(flags, ",") {
+			switch flag {
+			case "":
+			case "optional":
+				optional = true
+			default:
+				return fmt.Errorf("bnf: unknown flag %q in tag of %s", flag, fieldPath)
+			}
+		}
+		if rule == "" {
+			return fmt.Errorf("bnf: tag of %s names no rule", fieldPath)
+		}
+
+		found := findRule(n, rule)
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Slice && f.Type.Elem().Kind() != reflect.Uint8 {
+			s := reflect.MakeSlice(f.Type, len(found), len(found))
+			for j, m := range found {
+				if err := unmarshalNode(m, s.Index(j), fmt.Sprintf("%s[%d]", fieldPath, j), text); err != nil {
+					return err
+				}
+			}
+			fv.Set(s)
+			continue
+		}
+
+		switch {
+		case len(found) == 0 && optional:
+			fv.SetZero()
+		case len(found) == 0:
+			return &UnmarshalError{Pos: n.Pos, Rule: n.Type, Field: fieldPath, Msg: fmt.Sprintf("no <%s> found, mark the field optional if it may be missing", rule)}
+		case len(found) > 1:
+			return &UnmarshalError{Pos: found[1].Pos, Rule: rule, Field: fieldPath, Msg: fmt.Sprintf("<%s> matched %d times, use a slice", rule, len(found))}
+		default:
+			if err := unmarshalNode(found[0], fv, fieldPath, text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findRule returns the nodes of the rule below n in input order, not looking into found nodes.
+func findRule(n *ASTNode, rule string) []*ASTNode {
+	var out []*ASTNode
+	for _, child := range n.Children {
+		if child.Type == rule {
+			out = append(out, child)
+		} else {
+			out = append(out, findRule(child, rule)...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package bnf_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tgagor/go-bnf/bnf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ampm string
+
+func (a *ampm) UnmarshalText(text []byte) error {
+	*a = ampm(strings.ToUpper(string(text)))
+	return nil
+}
+
+type clock struct {
+	Hour   int      `bnf:"hour"`
+	Minute *int     `bnf:"minute,optional"`
+	AmPm   ampm     `bnf:"ampm,optional"`
+	Digits []string `bnf:"digit"`
+	Node   *bnf.ASTNode
+	Raw    *bnf.ASTNode `bnf:"hour"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+
+	tree, err := g.Parse("7:38pm")
+	assert.NoError(t, err)
+	var c clock
+	assert.NoError(t, bnf.Unmarshal(tree, &c))
+	assert.Equal(t, 7, c.Hour)
+	if assert.NotNil(t, c.Minute) {
+		assert.Equal(t, 38, *c.Minute)
+	}
+	assert.Equal(t, ampm("PM"), c.AmPm)
+	assert.Equal(t, []string{"7", "3", "8"}, c.Digits)
+	assert.Nil(t, c.Node)
+	assert.Equal(t, "hour", c.Raw.Type)
+
+	tree, err = g.Parse("23")
+	assert.NoError(t, err)
+	c = clock{}
+	assert.NoError(t, bnf.Unmarshal(tree, &c))
+	assert.Equal(t, 23, c.Hour)
+	assert.Nil(t, c.Minute)
+	assert.Equal(t, ampm(""), c.AmPm)
+	assert.Equal(t, []string{"2", "3"}, c.Digits)
+}
+
+func TestUnmarshal_Nested(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/postal.bnf")
+	assert.NoError(t, err)
+	tree, err := g.Parse("John Smith\n42 Elm St Apt12\nBoston, NY 10001\n")
+	assert.NoError(t, err)
+
+	type street struct {
+		Number uint   `bnf:"house-num"`
+		Name   string `bnf:"street-name"`
+		Apt    []byte `bnf:"apt-num,optional"`
+	}
+	var address struct {
+		Name   string  `bnf:"name-part"`
+		Street *street `bnf:"street-address"`
+		Zip    struct {
+			Town  string `bnf:"town-name"`
+			State string `bnf:"state-code"`
+			Code  string `bnf:"ZIP-code"`
+		} `bnf:"zip-part"`
+	}
+	assert.NoError(t, bnf.Unmarshal(tree, &address))
+	assert.Equal(t, "John Smith\n", address.Name)
+	assert.Equal(t, &street{Number: 42, Name: "Elm St", Apt: []byte("12")}, address.Street)
+	assert.Equal(t, "Boston", address.Zip.Town)
+	assert.Equal(t, "NY", address.Zip.State)
+	assert.Equal(t, "10001", address.Zip.Code)
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarFile("../examples/hour.bnf")
+	assert.NoError(t, err)
+	tree, err := g.Parse("7:38")
+	assert.NoError(t, err)
+
+	var required struct {
+		AmPm string `bnf:"ampm"`
+	}
+	err = bnf.Unmarshal(tree, &required)
+	assert.EqualError(t, err, "bnf: cannot unmarshal <time> into .AmPm at offset 0: no <ampm> found, mark the field optional if it may be missing")
+
+	var single struct {
+		Digit string `bnf:"digit"`
+	}
+	err = bnf.Unmarshal(tree, &single)
+	var uerr *bnf.UnmarshalError
+	if assert.ErrorAs(t, err, &uerr) {
+		assert.Equal(t, "digit", uerr.Rule)
+		assert.Contains(t, uerr.Msg, "use a slice")
+	}
+
+	var notNumber struct {
+		Time bool `bnf:"minute"`
+	}
+	assert.ErrorContains(t, bnf.Unmarshal(tree, &notNumber), `"38" is not a valid bool`)
+
+	var badTag struct {
+		Hour string `bnf:"hour,required"`
+	}
+	assert.ErrorContains(t, bnf.Unmarshal(tree, &badTag), `unknown flag "required"`)
+
+	assert.Error(t, bnf.Unmarshal(tree, required))
+	assert.Error(t, bnf.Unmarshal(nil, &required))
+}
+
+func TestGrammar_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	g, err := bnf.LoadGrammarString(`
+call  ::= name "(" (-args) ")"
+name  ::= /[a-z]+/
+args  ::= /[a-z]+/ ("," /[a-z]+/)*
+@skip ::= " "*
+`)
+	assert.NoError(t, err)
+
+	var c struct {
+		Name string `bnf:"name"`
+	}
+	assert.NoError(t, g.Unmarshal("f(a, b)", &c))
+	assert.Equal(t, "f", c.Name)
+
+	tree, err := g.Parse("f(a, b)")
+	assert.NoError(t, err)
+	var text string
+	assert.NoError(t, g.Unmarshal("f(a, b)", &text))
+	assert.Equal(t, "f(a, b)", text)
+	assert.NoError(t, bnf.Unmarshal(tree, &text))
+	assert.Equal(t, "f()", text)
+
+	assert.Error(t, g.Unmarshal("f(", &text))
+}
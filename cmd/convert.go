@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Convert handles the `convert` subcommand which exports a grammar to the syntax of another toolchain.
+type Convert struct {
+	GrammarFile string
+	To          string // one of bnf.ExportFormats
+	Name        string // grammar name for formats needing one, the file name by default
+	OutputFile  string // file to write, stdout when empty
+	Output      io.Writer
+	Warnings    io.Writer // where to report constructs which could not be converted, stderr by default
+}
+
+// Run parses the grammar and writes it in the target format, reporting warnings.
+func (c *Convert) Run() error {
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+	if c.Warnings == nil {
+		c.Warnings = os.Stderr
+	}
+
+	ast, err := bnf.LoadGrammarAST(c.GrammarFile)
+	if err != nil {
+		return fmt.Errorf("parsing error: %w", err)
+	}
+
+	name := c.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(c.GrammarFile), filepath.Ext(c.GrammarFile))
+	}
+
+	out := c.Output
+	if c.OutputFile != "" {
+		file, err := os.Create(c.OutputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	warnings, err := bnf.ExportGrammar(out, ast, bnf.ExportOptions{Format: c.To, Name: name})
+	for _, w := range warnings {
+		fmt.Fprintln(c.Warnings, "warning:", w)
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert_Run(t *testing.T) {
+	t.Parallel()
+	out, warnings := &bytes.Buffer{}, &bytes.Buffer{}
+
+	c := &Convert{GrammarFile: filepath.Join("..", "examples", "left-recursive.bnf"), To: "antlr", Output: out, Warnings: warnings}
+	assert.NoError(t, c.Run())
+	assert.Equal(t, "grammar Left_recursive;\n\nlist : list ',' id | id ;\nid   : 'a' | 'b' | 'c' ;\n", out.String())
+	assert.Empty(t, warnings.String())
+
+	out.Reset()
+	c = &Convert{GrammarFile: filepath.Join("..", "examples", "left-recursive.bnf"), To: "peg", Output: out, Warnings: warnings}
+	assert.NoError(t, c.Run())
+	assert.Contains(t, warnings.String(), "warning: rule <list> is left-recursive, which PEG does not support\n")
+}
+
+func TestConvert_OutputFile(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "hour.abnf")
+
+	c := &Convert{GrammarFile: filepath.Join("..", "examples", "hour.bnf"), To: "abnf", OutputFile: file, Warnings: &bytes.Buffer{}}
+	assert.NoError(t, c.Run())
+	src, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), "time   = hour [\":\" minute] [ampm]\n")
+}
+
+func TestConvert_UnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	c := &Convert{GrammarFile: filepath.Join("..", "examples", "hour.bnf"), To: "yacc", Output: &bytes.Buffer{}, Warnings: &bytes.Buffer{}}
+	assert.ErrorContains(t, c.Run(), "unknown export format")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Corpus handles the `corpus` subcommand which writes a coverage-guided test corpus for a grammar.
+type Corpus struct {
+	GrammarFile string
+	StartRule   string
+	OutputDir   string    // write one file per case here, otherwise NDJSON goes to Output
+	Output      io.Writer // NDJSON corpus or summary
+	Warnings    io.Writer // uncovered points
+}
+
+type corpusLine struct {
+	Input  string   `json:"input"`
+	Covers []string `json:"covers"`
+}
+
+// Run loads the grammar, generates the corpus and reports points that could not be covered.
+func (c *Corpus) Run() error {
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+	if c.Warnings == nil {
+		c.Warnings = os.Stderr
+	}
+	g, err := loadGrammar(c.GrammarFile, c.StartRule)
+	if err != nil {
+		return err
+	}
+
+	cases, uncovered, err := g.CoverageCorpus()
+	if err != nil {
+		return fmt.Errorf("generation error: %w", err)
+	}
+
+	if c.OutputDir == "" {
+		enc := json.NewEncoder(c.Output)
+		enc.SetEscapeHTML(false)
+		for _, cs := range cases {
+			if err := enc.Encode(corpusLine{Input: cs.Input, Covers: pointNames(cs.Covers)}); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := os.MkdirAll(c.OutputDir, 0o755); err != nil {
+			return err
+		}
+		for i, cs := range cases {
+			name := filepath.Join(c.OutputDir, fmt.Sprintf("case-%04d.txt", i+1))
+			if err := os.WriteFile(name, []byte(cs.Input), 0o644); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(c.Output, "Wrote %d cases to %s\n", len(cases), c.OutputDir)
+	}
+
+	for _, p := range uncovered {
+		fmt.Fprintln(c.Warnings, "not covered:", p)
+	}
+	return nil
+}
+
+func pointNames(points []bnf.CoveragePoint) []string {
+	out := make([]string, len(points))
+	for i, p := range points {
+		out[i] = p.String()
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorpus_NDJSON(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "left-recursive.bnf")
+	out := &bytes.Buffer{}
+	warnings := &bytes.Buffer{}
+
+	c := &Corpus{GrammarFile: grammarFile, Output: out, Warnings: warnings}
+	assert.NoError(t, c.Run())
+	assert.Empty(t, warnings.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.NotEmpty(t, lines)
+	for _, l := range lines {
+		var line corpusLine
+		assert.NoError(t, json.Unmarshal([]byte(l), &line))
+		assert.NotEmpty(t, line.Input)
+		assert.NotEmpty(t, line.Covers)
+	}
+}
+
+func TestCorpus_Directory(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "postal.bnf")
+	dir := t.TempDir()
+	out := &bytes.Buffer{}
+
+	c := &Corpus{GrammarFile: grammarFile, OutputDir: dir, Output: out, Warnings: &bytes.Buffer{}}
+	assert.NoError(t, c.Run())
+	assert.Contains(t, out.String(), "Wrote")
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	// every case is a valid input for the grammar
+	for _, f := range files {
+		cli := New("0.0.1", "test", grammarFile, filepath.Join(dir, f.Name()), false, false, false, "", &bytes.Buffer{})
+		assert.NoError(t, cli.Run())
+		assert.Contains(t, cli.Output.(*bytes.Buffer).String(), "-> matched")
+	}
+}
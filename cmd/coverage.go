@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Coverage handles the `coverage` subcommand which reports the grammar elements a corpus exercises.
+type Coverage struct {
+	GrammarFile string
+	StartRule   string
+	InputFiles  []string // files or glob patterns
+	LineByLine  bool
+	Format      string // text, json or html
+	Output      io.Writer
+}
+
+type coverageJSON struct {
+	Inputs  int             `json:"inputs"`
+	Matched int             `json:"matched"`
+	Percent float64         `json:"percent"`
+	Points  []coveragePoint `json:"points"`
+}
+
+type coveragePoint struct {
+	Rule   string `json:"rule"`
+	Kind   string `json:"kind"`
+	Index  int    `json:"index,omitempty"`
+	Branch int    `json:"branch"`
+	Expr   string `json:"expr"`
+	Hits   int    `json:"hits"`
+}
+
+// Run loads the grammar, matches every input and writes the coverage report.
+func (c *Coverage) Run() error {
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+	g, err := loadGrammar(c.GrammarFile, c.StartRule)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, pattern := range c.InputFiles {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no input files match %s", pattern)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no input provided: use -i <files>")
+	}
+
+	cov := bnf.NewCoverage(g)
+	for _, f := range files {
+		inputs, err := loadFile(f, c.LineByLine)
+		if err != nil {
+			return err
+		}
+		for _, in := range inputs {
+			if _, err := cov.Match(in); err != nil {
+				if _, ok := err.(*bnf.ParseError); !ok {
+					return fmt.Errorf("%s: %w", f, err)
+				}
+			}
+		}
+	}
+
+	switch c.Format {
+	case "", "text":
+		return writeCoverageText(c.Output, cov)
+	case "json":
+		return writeCoverageJSON(c.Output, cov)
+	case "html":
+		return writeCoverageHTML(c.Output, cov)
+	}
+	return fmt.Errorf("unknown report format: %s", c.Format)
+}
+
+// ruleCoverage summarizes coverage points per rule, in definition order.
+type ruleCoverage struct {
+	Rule           string
+	Covered, Total int
+}
+
+func summarize(report []bnf.PointCoverage) []ruleCoverage {
+	var out []ruleCoverage
+	for _, p := range report {
+		if len(out) == 0 || out[len(out)-1].Rule != p.Rule {
+			out = append(out, ruleCoverage{Rule: p.Rule})
+		}
+		out[len(out)-1].Total++
+		if p.Hits > 0 {
+			out[len(out)-1].Covered++
+		}
+	}
+	return out
+}
+
+func writeCoverageText(w io.Writer, cov *bnf.Coverage) error {
+	report := cov.Report()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, r := range summarize(report) {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.1f%%\t\n", r.Rule, r.Covered, r.Total, 100*float64(r.Covered)/float64(r.Total))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d of %d inputs matched, coverage: %.1f%% of grammar points\n", cov.Matched, cov.Inputs, cov.Percent())
+
+	first := true
+	for _, p := range report {
+		if p.Hits > 0 {
+			continue
+		}
+		if first {
+			fmt.Fprintln(w, "\nNot covered:")
+			first = false
+		}
+		fmt.Fprintf(w, "  %s\n", p)
+	}
+	return nil
+}
+
+func writeCoverageJSON(w io.Writer, cov *bnf.Coverage) error {
+	out := coverageJSON{Inputs: cov.Inputs, Matched: cov.Matched, Percent: cov.Percent()}
+	for _, p := range cov.Report() {
+		out.Points = append(out.Points, coveragePoint{
+			Rule:   p.Rule,
+			Kind:   p.Kind,
+			Index:  p.Index,
+			Branch: p.Branch,
+			Expr:   p.Expr,
+			Hits:   p.Hits,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+var coverageHTML = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Grammar coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 8px; text-align: left; }
+td.expr { font-family: monospace; }
+tr.hit { background: #dfd; }
+tr.miss { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Grammar coverage: {{printf "%.1f" .Percent}}%</h1>
+<p>{{.Matched}} of {{.Inputs}} inputs matched.</p>
+<table>
+<tr><th>Rule</th><th>Element</th><th>Expression</th><th>Hits</th></tr>
+{{range .Points}}<tr class="{{if .Hits}}hit{{else}}miss{{end}}"><td>{{.Rule}}</td><td>{{.Label}}</td><td class="expr">{{.Expr}}</td><td>{{.Hits}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type htmlPoint struct {
+	bnf.PointCoverage
+	Label string
+}
+
+func writeCoverageHTML(w io.Writer, cov *bnf.Coverage) error {
+	var points []htmlPoint
+	for _, p := range cov.Report() {
+		label := p.Kind
+		switch p.Kind {
+		case bnf.CoverAlternative:
+			label = fmt.Sprintf("choice #%d alternative %d", p.Index, p.Branch+1)
+		case bnf.CoverRepeat:
+			label = fmt.Sprintf("repetition #%d %s", p.Index, [...]string{"0 times", "once", "many times"}[p.Branch])
+		}
+		points = append(points, htmlPoint{PointCoverage: p, Label: label})
+	}
+	return coverageHTML.Execute(w, map[string]any{
+		"Percent": cov.Percent(),
+		"Inputs":  cov.Inputs,
+		"Matched": cov.Matched,
+		"Points":  points,
+	})
+}
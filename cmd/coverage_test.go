@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverage_Text(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+
+	c := &Coverage{
+		GrammarFile: filepath.Join("..", "examples", "hour.bnf"),
+		InputFiles:  []string{filepath.Join("..", "examples", "hour.test")},
+		LineByLine:  true,
+		Output:      out,
+	}
+	assert.NoError(t, c.Run())
+	assert.Contains(t, out.String(), "5 of 5 inputs matched")
+	assert.Contains(t, out.String(), "Not covered:")
+	assert.Contains(t, out.String(), `digit: choice #1 alternative 6: "5"`)
+}
+
+func TestCoverage_JSON(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+
+	c := &Coverage{
+		GrammarFile: filepath.Join("..", "examples", "postal.bnf"),
+		InputFiles:  []string{filepath.Join("..", "examples", "postal*.txt")},
+		Format:      "json",
+		Output:      out,
+	}
+	assert.NoError(t, c.Run())
+
+	var report coverageJSON
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, 4, report.Inputs)
+	assert.Equal(t, 4, report.Matched)
+	assert.NotEmpty(t, report.Points)
+	assert.Greater(t, report.Percent, 0.0)
+}
+
+func TestCoverage_HTML(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+
+	c := &Coverage{
+		GrammarFile: filepath.Join("..", "examples", "hour.bnf"),
+		InputFiles:  []string{filepath.Join("..", "examples", "hour.test")},
+		LineByLine:  true,
+		Format:      "html",
+		Output:      out,
+	}
+	assert.NoError(t, c.Run())
+	assert.Contains(t, out.String(), "<h1>Grammar coverage:")
+	assert.Contains(t, out.String(), `class="miss"`)
+}
+
+func TestCoverage_Errors(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "hour.bnf")
+
+	c := &Coverage{GrammarFile: grammarFile, Output: &bytes.Buffer{}}
+	assert.ErrorContains(t, c.Run(), "no input")
+
+	c = &Coverage{GrammarFile: grammarFile, InputFiles: []string{"missing-*.txt"}, Output: &bytes.Buffer{}}
+	assert.ErrorContains(t, c.Run(), "no input files match")
+
+	c = &Coverage{
+		GrammarFile: grammarFile,
+		InputFiles:  []string{filepath.Join("..", "examples", "hour.test")},
+		Format:      "xml",
+		Output:      &bytes.Buffer{},
+	}
+	assert.ErrorContains(t, c.Run(), "unknown report format")
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Enumerate handles the `enumerate` subcommand which lists the language of a grammar in shortlex order.
+type Enumerate struct {
+	GrammarFile string
+	StartRule   string
+	MaxLen      int
+	MaxDepth    int
+	MaxCount    int
+	Output      io.Writer
+}
+
+// Run loads the grammar and writes every sentence within the bounds, one per line.
+func (e *Enumerate) Run() error {
+	if e.Output == nil {
+		e.Output = os.Stdout
+	}
+	g, err := loadGrammar(e.GrammarFile, e.StartRule)
+	if err != nil {
+		return err
+	}
+
+	sentences, err := g.Enumerate(bnf.EnumerateOptions{
+		MaxLen:   e.MaxLen,
+		MaxDepth: e.MaxDepth,
+		MaxCount: e.MaxCount,
+	})
+	if err != nil {
+		return fmt.Errorf("enumeration error: %w", err)
+	}
+	for _, s := range sentences {
+		fmt.Fprintln(e.Output, s)
+	}
+	return nil
+}
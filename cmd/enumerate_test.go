@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumerate_Run(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "left-recursive.bnf")
+	out := &bytes.Buffer{}
+
+	e := &Enumerate{GrammarFile: grammarFile, MaxLen: 3, Output: out}
+	assert.NoError(t, e.Run())
+	assert.Equal(t, "a\nb\nc\na,a\na,b\na,c\nb,a\nb,b\nb,c\nc,a\nc,b\nc,c\n", out.String())
+}
+
+func TestEnumerate_NoBound(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "left-recursive.bnf")
+
+	e := &Enumerate{GrammarFile: grammarFile, Output: &bytes.Buffer{}}
+	assert.ErrorContains(t, e.Run(), "bound")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Fmt handles the `fmt` subcommand which rewrites grammars in canonical form, like gofmt.
+type Fmt struct {
+	Files  []string // grammar files, standard input when empty
+	Write  bool     // write the result back to the files instead of printing it
+	Diff   bool     // print a diff instead of the formatted grammar
+	Input  io.Reader
+	Output io.Writer
+}
+
+// Run formats every file, printing, diffing or rewriting it.
+func (f *Fmt) Run() error {
+	if f.Output == nil {
+		f.Output = os.Stdout
+	}
+	if len(f.Files) == 0 {
+		if f.Write {
+			return fmt.Errorf("cannot use -w with standard input")
+		}
+		if f.Input == nil {
+			f.Input = os.Stdin
+		}
+		src, err := io.ReadAll(f.Input)
+		if err != nil {
+			return err
+		}
+		return f.format("<standard input>", src)
+	}
+
+	for _, file := range f.Files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := f.format(file, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fmt) format(name string, src []byte) error {
+	out, err := bnf.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if f.Diff && !bytes.Equal(src, out) {
+		fmt.Fprint(f.Output, unifiedDiff(name, string(src), string(out)))
+	}
+	if f.Write {
+		if bytes.Equal(src, out) {
+			return nil
+		}
+		return os.WriteFile(name, out, 0o644)
+	}
+	if !f.Diff {
+		_, err = f.Output.Write(out)
+	}
+	return err
+}
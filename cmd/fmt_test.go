@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const unformatted = `# times
+time::=hour (':' minute)?
+hour ::= digit digit
+minute ::= digit digit
+digit ::= "0" | "1"
+`
+
+const formatted = `# times
+time   ::= hour (":" minute)?
+hour   ::= digit digit
+minute ::= digit digit
+digit  ::= "0" | "1"
+`
+
+func TestFmt_Stdin(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+
+	f := &Fmt{Input: strings.NewReader(unformatted), Output: out}
+	assert.NoError(t, f.Run())
+	assert.Equal(t, formatted, out.String())
+
+	f = &Fmt{Write: true, Input: strings.NewReader(unformatted), Output: out}
+	assert.Error(t, f.Run())
+}
+
+func TestFmt_Diff(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+
+	f := &Fmt{Diff: true, Input: strings.NewReader(unformatted), Output: out}
+	assert.NoError(t, f.Run())
+	assert.Equal(t, `--- <standard input>.orig
++++ <standard input>
+@@ -1,5 +1,5 @@
+ # times
+-time::=hour (':' minute)?
+-hour ::= digit digit
++time   ::= hour (":" minute)?
++hour   ::= digit digit
+ minute ::= digit digit
+-digit ::= "0" | "1"
++digit  ::= "0" | "1"
+`, out.String())
+
+	// formatted input has no diff
+	out.Reset()
+	f = &Fmt{Diff: true, Input: strings.NewReader(formatted), Output: out}
+	assert.NoError(t, f.Run())
+	assert.Empty(t, out.String())
+}
+
+func TestFmt_Write(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "time.bnf")
+	assert.NoError(t, os.WriteFile(file, []byte(unformatted), 0o644))
+
+	f := &Fmt{Files: []string{file}, Write: true, Output: &bytes.Buffer{}}
+	assert.NoError(t, f.Run())
+
+	src, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, formatted, string(src))
+}
+
+func TestFmt_InvalidGrammar(t *testing.T) {
+	t.Parallel()
+
+	f := &Fmt{Input: strings.NewReader("a ::= (b"), Output: &bytes.Buffer{}}
+	assert.ErrorContains(t, f.Run(), "<standard input>")
+}
+
+func TestUnifiedDiff_Hunks(t *testing.T) {
+	t.Parallel()
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\nx\n"
+
+	assert.Equal(t, `--- f.orig
++++ f
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,4 +10,4 @@
+ 9
+ 10
+ 11
+-12
++x
+`, unifiedDiff("f", a, b))
+	assert.Empty(t, unifiedDiff("f", a, a))
+}
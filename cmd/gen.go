@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// Gen handles the `gen` subcommand which prints random sentences of a grammar.
+type Gen struct {
+	GrammarFile string
+	StartRule   string
+	Count       int
+	Seed        uint64
+	MaxDepth    int
+	MaxRepeat   int
+	MaxLength   int
+	Invalid     bool   // print near-miss inputs rejected by the grammar instead
+	InputFile   string // valid inputs, one per line, to derive invalid ones from instead of generated sentences
+	Output      io.Writer
+}
+
+// Run loads the grammar and writes Count generated sentences, one per line.
+func (gen *Gen) Run() error {
+	if gen.Output == nil {
+		gen.Output = os.Stdout
+	}
+	g, err := loadGrammar(gen.GrammarFile, gen.StartRule)
+	if err != nil {
+		return err
+	}
+
+	r := rand.New(rand.NewPCG(gen.Seed, gen.Seed))
+	opts := bnf.GenerateOptions{
+		MaxDepth:  gen.MaxDepth,
+		MaxRepeat: gen.MaxRepeat,
+		MaxLength: gen.MaxLength,
+	}
+	if gen.Invalid {
+		return gen.runInvalid(g, r, opts)
+	}
+
+	for range gen.Count {
+		s, err := g.Generate(r, opts)
+		if err != nil {
+			return fmt.Errorf("generation error: %w", err)
+		}
+		fmt.Fprintln(gen.Output, s)
+	}
+	return nil
+}
+
+// runInvalid prints Count near-miss inputs, each mutated from a valid seed.
+func (gen *Gen) runInvalid(g *bnf.Grammar, r *rand.Rand, opts bnf.GenerateOptions) error {
+	var seeds []string
+	if gen.InputFile != "" {
+		lines, err := loadFile(gen.InputFile, true)
+		if err != nil {
+			return err
+		}
+		for _, l := range lines {
+			if l != "" {
+				seeds = append(seeds, l)
+			}
+		}
+		if len(seeds) == 0 {
+			return fmt.Errorf("no valid inputs in %s", gen.InputFile)
+		}
+	}
+
+	printed := 0
+	for attempt := 0; printed < gen.Count; attempt++ {
+		if attempt >= 100*gen.Count {
+			return fmt.Errorf("could not derive invalid inputs, only %d of %d printed", printed, gen.Count)
+		}
+
+		var s string
+		if seeds != nil {
+			s = seeds[attempt%len(seeds)]
+		} else {
+			var err error
+			if s, err = g.Generate(r, opts); err != nil {
+				return fmt.Errorf("generation error: %w", err)
+			}
+		}
+
+		mutants, err := g.Mutate(r, s, 1)
+		if err != nil {
+			return fmt.Errorf("input %q does not match the grammar: %w", s, err)
+		}
+		for _, m := range mutants {
+			fmt.Fprintln(gen.Output, m)
+			printed++
+		}
+	}
+	return nil
+}
+
+// loadGrammar loads and validates a grammar file, optionally overriding its start rule.
+func loadGrammar(file, startRule string) (*bnf.Grammar, error) {
+	g, err := bnf.LoadGrammarFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("parsing error: %w", err)
+	}
+	if startRule != "" {
+		g.SetStart(startRule)
+	}
+	if err := g.ValidateGrammar(); err != nil {
+		return nil, fmt.Errorf("grammar validation error: %w", err)
+	}
+	return g, nil
+}
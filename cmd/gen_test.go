@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+func TestGen_Run(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "hour.bnf")
+	out := &bytes.Buffer{}
+
+	gen := &Gen{GrammarFile: grammarFile, Count: 20, Seed: 42, Output: out}
+	assert.NoError(t, gen.Run())
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 20)
+
+	g, err := bnf.LoadGrammarFile(grammarFile)
+	assert.NoError(t, err)
+	for _, l := range lines {
+		ok, err := g.Match(l)
+		assert.True(t, ok, l)
+		assert.NoError(t, err)
+	}
+
+	// same seed, same output
+	again := &bytes.Buffer{}
+	gen = &Gen{GrammarFile: grammarFile, Count: 20, Seed: 42, Output: again}
+	assert.NoError(t, gen.Run())
+	assert.Equal(t, out.String(), again.String())
+}
+
+func TestGen_InvalidGrammar(t *testing.T) {
+	t.Parallel()
+
+	gen := &Gen{GrammarFile: filepath.Join("..", "tests", "invalid.bnf"), Count: 1, Output: &bytes.Buffer{}}
+	err := gen.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined rule")
+}
+
+func TestGen_Invalid(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "hour.bnf")
+	out := &bytes.Buffer{}
+
+	gen := &Gen{GrammarFile: grammarFile, Count: 20, Seed: 42, Invalid: true, Output: out}
+	assert.NoError(t, gen.Run())
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 20)
+
+	g, err := bnf.LoadGrammarFile(grammarFile)
+	assert.NoError(t, err)
+	for _, l := range lines {
+		ok, _ := g.Match(l)
+		assert.False(t, ok, l)
+	}
+}
+
+func TestGen_InvalidFromInputs(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "hour.bnf")
+	out := &bytes.Buffer{}
+
+	gen := &Gen{
+		GrammarFile: grammarFile,
+		Count:       5,
+		Seed:        1,
+		Invalid:     true,
+		InputFile:   filepath.Join("..", "examples", "hour.test"),
+		Output:      out,
+	}
+	assert.NoError(t, gen.Run())
+	assert.Len(t, strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"), 5)
+
+	// inputs must be valid to begin with
+	gen = &Gen{
+		GrammarFile: grammarFile,
+		Count:       1,
+		Invalid:     true,
+		InputFile:   filepath.Join("..", "examples", "postal1.txt"),
+		Output:      &bytes.Buffer{},
+	}
+	assert.ErrorContains(t, gen.Run(), "does not match the grammar")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/tgagor/go-bnf/bnf"
+)
+
+// GenGo handles the `gen-go` subcommand which writes a standalone Go parser for a grammar.
+type GenGo struct {
+	GrammarFile string
+	StartRule   string
+	Package     string
+	Types       bool   // also generate typed AST structs and converters
+	TypesOnly   bool   // generate only the typed AST, converting from bnf.ASTNode
+	OutputFile  string // file to write, stdout when empty
+	Output      io.Writer
+}
+
+// Run loads the grammar and writes the generated parser to OutputFile or Output.
+func (gg *GenGo) Run() error {
+	if gg.Output == nil {
+		gg.Output = os.Stdout
+	}
+	g, err := loadGrammar(gg.GrammarFile, gg.StartRule)
+	if err != nil {
+		return err
+	}
+
+	var src bytes.Buffer
+	opts := bnf.GoOptions{Package: gg.Package, Source: filepath.Base(gg.GrammarFile), Types: gg.Types}
+	generate := g.GenerateGo
+	if gg.TypesOnly {
+		generate = g.GenerateGoTypes
+	}
+	if err := generate(&src, opts); err != nil {
+		return fmt.Errorf("code generation error: %w", err)
+	}
+
+	if gg.OutputFile == "" {
+		_, err = gg.Output.Write(src.Bytes())
+		return err
+	}
+	return os.WriteFile(gg.OutputFile, src.Bytes(), 0o644)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenGo_Run(t *testing.T) {
+	t.Parallel()
+	out := &bytes.Buffer{}
+
+	gg := &GenGo{GrammarFile: filepath.Join("..", "examples", "hour.bnf"), Package: "hour", Output: out}
+	assert.NoError(t, gg.Run())
+	assert.Contains(t, out.String(), "// Code generated by bnf gen-go from hour.bnf. DO NOT EDIT.")
+	assert.Contains(t, out.String(), "package hour")
+	assert.Contains(t, out.String(), "func Parse(input string) (*ASTNode, error)")
+}
+
+func TestGenGo_OutputFile(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "parser.go")
+
+	gg := &GenGo{GrammarFile: filepath.Join("..", "examples", "numbers.bnf"), OutputFile: file}
+	assert.NoError(t, gg.Run())
+
+	src, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), "package parser")
+}
+
+func TestGenGo_InvalidGrammar(t *testing.T) {
+	t.Parallel()
+
+	gg := &GenGo{GrammarFile: filepath.Join("..", "tests", "invalid.bnf"), Output: &bytes.Buffer{}}
+	assert.ErrorContains(t, gg.Run(), "undefined rule")
+}
+
+func TestGenGo_Types(t *testing.T) {
+	t.Parallel()
+	grammarFile := filepath.Join("..", "examples", "hour.bnf")
+
+	out := &bytes.Buffer{}
+	gg := &GenGo{GrammarFile: grammarFile, Types: true, Output: out}
+	assert.NoError(t, gg.Run())
+	assert.Contains(t, out.String(), "func Parse(input string)")
+	assert.Contains(t, out.String(), "func ToTime(n *ASTNode) (*Time, error)")
+
+	out.Reset()
+	gg = &GenGo{GrammarFile: grammarFile, TypesOnly: true, Output: out}
+	assert.NoError(t, gg.Run())
+	assert.NotContains(t, out.String(), "func Parse(input string)")
+	assert.Contains(t, out.String(), "type ASTNode = bnf.ASTNode")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/tgagor/go-bnf/bnf"
+	"github.com/tgagor/go-bnf/cmd"
+)
+
+var errMissingGrammar = errors.New("no grammar provided: use -g <grammar-file>")
+
+// subcommands maps the first command-line argument to its handler.
+// Without a known subcommand bnf falls back to matching input against a grammar.
+var subcommands = map[string]func(args []string) error{
+	"gen":       runGen,
+	"enumerate": runEnumerate,
+	"corpus":    runCorpus,
+	"coverage":  runCoverage,
+	"gen-go":    runGenGo,
+	"fmt":       runFmt,
+	"convert":   runConvert,
+}
+
+func runGen(args []string) error {
+	gen := &cmd.Gen{}
+	fs := flag.NewFlagSet(appName+" gen", flag.ExitOnError)
+	fs.StringVar(&gen.GrammarFile, "g", "", "Path to the BNF grammar file")
+	fs.StringVar(&gen.StartRule, "s", "", "Override the start rule for the grammar")
+	fs.IntVar(&gen.Count, "n", 10, "Number of sentences to generate")
+	fs.Uint64Var(&gen.Seed, "seed", 0, "Random seed (random when not set)")
+	fs.IntVar(&gen.MaxDepth, "max-depth", 12, "Rule nesting after which the shortest derivations are preferred")
+	fs.IntVar(&gen.MaxRepeat, "max-repeat", 3, "Maximum iterations of unbounded repetitions")
+	fs.IntVar(&gen.MaxLength, "max-len", 0, "Sentence length after which the shortest derivations are preferred (0 = no limit)")
+	fs.BoolVar(&gen.Invalid, "invalid", false, "Generate near-miss inputs rejected by the grammar")
+	fs.StringVar(&gen.InputFile, "i", "", "Valid inputs, one per line, to mutate with --invalid instead of generated ones")
+	fs.Parse(args)
+
+	if !isFlagSet(fs, "seed") {
+		gen.Seed = rand.Uint64()
+	}
+	return requireGrammar(fs, gen.GrammarFile, gen.Run)
+}
+
+func runEnumerate(args []string) error {
+	e := &cmd.Enumerate{}
+	fs := flag.NewFlagSet(appName+" enumerate", flag.ExitOnError)
+	fs.StringVar(&e.GrammarFile, "g", "", "Path to the BNF grammar file")
+	fs.StringVar(&e.StartRule, "s", "", "Override the start rule for the grammar")
+	fs.IntVar(&e.MaxLen, "max-len", 0, "Maximum sentence length in characters")
+	fs.IntVar(&e.MaxDepth, "max-depth", 0, "Maximum rule nesting of a derivation")
+	fs.IntVar(&e.MaxCount, "n", 0, "Maximum number of sentences to list (0 = all)")
+	fs.Parse(args)
+
+	return requireGrammar(fs, e.GrammarFile, e.Run)
+}
+
+func runCorpus(args []string) error {
+	c := &cmd.Corpus{}
+	fs := flag.NewFlagSet(appName+" corpus", flag.ExitOnError)
+	fs.StringVar(&c.GrammarFile, "g", "", "Path to the BNF grammar file")
+	fs.StringVar(&c.StartRule, "s", "", "Override the start rule for the grammar")
+	fs.StringVar(&c.OutputDir, "o", "", "Directory to write one file per case (NDJSON to stdout when empty)")
+	fs.Parse(args)
+
+	return requireGrammar(fs, c.GrammarFile, c.Run)
+}
+
+func runCoverage(args []string) error {
+	c := &cmd.Coverage{}
+	var input string
+	fs := flag.NewFlagSet(appName+" coverage", flag.ExitOnError)
+	fs.StringVar(&c.GrammarFile, "g", "", "Path to the BNF grammar file")
+	fs.StringVar(&c.StartRule, "s", "", "Override the start rule for the grammar")
+	fs.StringVar(&input, "i", "", "Input files or glob pattern, further files may follow the flags")
+	fs.BoolVar(&c.LineByLine, "l", false, "Treat every line of the inputs as a separate input")
+	fs.StringVar(&c.Format, "format", "text", "Report format: text, json or html")
+	fs.Parse(args)
+
+	if input != "" {
+		c.InputFiles = append(c.InputFiles, input)
+	}
+	// a shell-expanded `-i corpus/*` leaves the remaining files as arguments
+	c.InputFiles = append(c.InputFiles, fs.Args()...)
+	return requireGrammar(fs, c.GrammarFile, c.Run)
+}
+
+func runGenGo(args []string) error {
+	gg := &cmd.GenGo{}
+	fs := flag.NewFlagSet(appName+" gen-go", flag.ExitOnError)
+	fs.StringVar(&gg.GrammarFile, "g", "", "Path to the BNF grammar file")
+	fs.StringVar(&gg.StartRule, "s", "", "Override the start rule for the grammar")
+	fs.StringVar(&gg.Package, "pkg", "parser", "Package name of the generated code")
+	fs.BoolVar(&gg.Types, "types", false, "Also generate typed AST structs and converters")
+	fs.BoolVar(&gg.TypesOnly, "types-only", false, "Generate only the typed AST, converting from bnf.ASTNode")
+	fs.StringVar(&gg.OutputFile, "o", "", "File to write the generated parser to (stdout when empty)")
+	fs.Parse(args)
+
+	return requireGrammar(fs, gg.GrammarFile, gg.Run)
+}
+
+func runFmt(args []string) error {
+	f := &cmd.Fmt{}
+	fs := flag.NewFlagSet(appName+" fmt", flag.ExitOnError)
+	fs.BoolVar(&f.Write, "w", false, "Write the result to the grammar files instead of stdout")
+	fs.BoolVar(&f.Diff, "d", false, "Print diffs instead of the formatted grammars")
+	fs.Parse(args)
+
+	f.Files = fs.Args()
+	return f.Run()
+}
+
+func runConvert(args []string) error {
+	c := &cmd.Convert{}
+	fs := flag.NewFlagSet(appName+" convert", flag.ExitOnError)
+	fs.StringVar(&c.GrammarFile, "g", "", "Path to the BNF grammar file")
+	fs.StringVar(&c.To, "to", "", "Target format: "+strings.Join(bnf.ExportFormats, ", "))
+	fs.StringVar(&c.Name, "name", "", "Grammar name for formats needing one (default: the file name)")
+	fs.StringVar(&c.OutputFile, "o", "", "File to write the converted grammar to (stdout when empty)")
+	fs.Parse(args)
+
+	return requireGrammar(fs, c.GrammarFile, c.Run)
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// requireGrammar prints the subcommand usage when no grammar file was given, otherwise runs it.
+func requireGrammar(fs *flag.FlagSet, grammarFile string, run func() error) error {
+	if grammarFile == "" {
+		fs.Usage()
+		return errMissingGrammar
+	}
+	return run()
+}
@@ -20,6 +20,15 @@ var parseAsAST bool
 var startRule string
 
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 
 	// parse arguments
 	flag.StringVar(&grammarFile, "g", "", "Path to the BNF grammar file")
@@ -35,6 +44,13 @@ func main() {
 	if help {
 		fmt.Println("Usage: bnf -g <grammar-file> -i <input-file> [options]")
 		fmt.Println("   or: cat <input-file> | bnf -g <grammar-file> [options]")
+		fmt.Println("   or: bnf gen -g <grammar-file> [-n count] [--seed seed]")
+		fmt.Println("   or: bnf enumerate -g <grammar-file> --max-len <n>")
+		fmt.Println("   or: bnf corpus -g <grammar-file> [-o <dir>]")
+		fmt.Println("   or: bnf coverage -g <grammar-file> -i <input-files...> [-format text|json|html]")
+		fmt.Println("   or: bnf gen-go -g <grammar-file> [-pkg name] [-types] [-o parser.go]")
+		fmt.Println("   or: bnf fmt [-w] [-d] [grammar-files...]")
+		fmt.Println("   or: bnf convert -g <grammar-file> --to ebnf|abnf|w3c|antlr|peg [-o file]")
 		flag.PrintDefaults()
 		os.Exit(0)
 	}